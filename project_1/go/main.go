@@ -2,9 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -76,9 +79,50 @@ func notFoundHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	// Параметры ограничения частоты запросов
+	var rateLimitEnabled bool
+	var defaultRate float64
+	var defaultBurst int
+	var routeLimits string
+	var idleTTL time.Duration
+	var apiKeys string
+
+	flag.BoolVar(&rateLimitEnabled, "ratelimit", false, "Включить ограничение частоты запросов")
+	flag.Float64Var(&defaultRate, "rate", 5, "Запросов в секунду на клиента по умолчанию")
+	flag.IntVar(&defaultBurst, "burst", 10, "Размер всплеска на клиента по умолчанию")
+	flag.StringVar(&routeLimits, "limits", "/=10:20,/slow=1:2", "Правила для маршрутов: маршрут=скорость:всплеск через запятую")
+	flag.DurationVar(&idleTTL, "idle-ttl", time.Minute, "Через сколько удалять ведра неактивных клиентов")
+	flag.StringVar(&apiKeys, "api-keys", "", "Известные API-ключи через запятую: клиент с таким X-API-Key получает свое ведро")
+	flag.Parse()
+
+	// Без ограничителя обработчики вызываются напрямую
+	wrap := func(route string, next http.HandlerFunc) http.HandlerFunc { return next }
+
+	if rateLimitEnabled {
+		rules, err := parseRateLimitRules(routeLimits)
+		if err != nil {
+			fmt.Printf("❌ Ошибка в параметре -limits: %v\n", err)
+			os.Exit(1)
+		}
+		if defaultRate <= 0 || defaultBurst < 1 || idleTTL <= 0 {
+			fmt.Println("❌ Ошибка: -rate, -burst и -idle-ttl должны быть положительными")
+			os.Exit(1)
+		}
+
+		var keys []string
+		for _, key := range strings.Split(apiKeys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+
+		limiter := NewRateLimiter(RateLimitRule{Rate: defaultRate, Burst: defaultBurst}, rules, keys, idleTTL)
+		wrap = limiter.Middleware
+	}
+
 	// Настраиваем маршруты
-	http.HandleFunc("/", fastHandler)
-	http.HandleFunc("/slow", slowHandler)
+	http.HandleFunc("/", wrap("/", fastHandler))
+	http.HandleFunc("/slow", wrap("/slow", slowHandler))
 	http.HandleFunc("/*", notFoundHandler)
 
 	// Запускаем сервер на порту 8080
@@ -87,6 +131,10 @@ func main() {
 	fmt.Printf("📊 Тестовые маршруты:\n")
 	fmt.Printf("   GET / - быстрый ответ\n")
 	fmt.Printf("   GET /slow - медленный ответ (10 сек)\n")
+	if rateLimitEnabled {
+		fmt.Printf("🚦 Ограничение запросов включено: %s (по умолчанию %.1f/с, всплеск %d)\n",
+			routeLimits, defaultRate, defaultBurst)
+	}
 	fmt.Printf("\n✅ Преимущество: Горутины позволяют обрабатывать множество запросов параллельно!\n")
 
 	// Запускаем сервер
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Правило ограничения для маршрута: скорость пополнения и размер "ведра"
+type RateLimitRule struct {
+	Rate  float64 // Сколько токенов добавляется в секунду
+	Burst int     // Максимальное количество токенов (размер всплеска)
+}
+
+// Ведро токенов для одного клиента на одном маршруте
+type tokenBucket struct {
+	tokens   float64   // Текущее количество токенов
+	last     time.Time // Время последнего пополнения
+	lastSeen time.Time // Время последнего обращения (для очистки)
+}
+
+// Ограничитель запросов: отдельное ведро для каждой пары клиент+маршрут
+type RateLimiter struct {
+	defaultRule RateLimitRule
+	routeRules  map[string]RateLimitRule
+	apiKeys     map[string]bool // Известные API-ключи: только они получают свое ведро
+	buckets     map[string]*tokenBucket
+	idleTTL     time.Duration
+	mutex       sync.Mutex
+}
+
+// Создаем ограничитель и запускаем фоновую очистку неактивных ведер
+func NewRateLimiter(defaultRule RateLimitRule, routeRules map[string]RateLimitRule, apiKeys []string, idleTTL time.Duration) *RateLimiter {
+	rl := &RateLimiter{
+		defaultRule: defaultRule,
+		routeRules:  routeRules,
+		apiKeys:     make(map[string]bool),
+		buckets:     make(map[string]*tokenBucket),
+		idleTTL:     idleTTL,
+	}
+
+	for _, key := range apiKeys {
+		rl.apiKeys[key] = true
+	}
+
+	go rl.evictLoop()
+
+	return rl
+}
+
+// Правило для маршрута (или правило по умолчанию)
+func (rl *RateLimiter) ruleFor(route string) RateLimitRule {
+	if rule, ok := rl.routeRules[route]; ok {
+		return rule
+	}
+	return rl.defaultRule
+}
+
+// Пытаемся забрать токен. Возвращаем: разрешен ли запрос, сколько токенов осталось
+// и через сколько времени ведро снова будет полным (или появится токен при отказе)
+func (rl *RateLimiter) allow(key string, rule RateLimitRule) (bool, int, time.Duration) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := time.Now()
+	bucket, exists := rl.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(rule.Burst), last: now}
+		rl.buckets[key] = bucket
+	}
+
+	// Пополняем ведро пропорционально прошедшему времени
+	elapsed := now.Sub(bucket.last).Seconds()
+	bucket.tokens = math.Min(float64(rule.Burst), bucket.tokens+elapsed*rule.Rate)
+	bucket.last = now
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / rule.Rate * float64(time.Second))
+		return false, 0, wait
+	}
+
+	bucket.tokens--
+	untilFull := time.Duration((float64(rule.Burst) - bucket.tokens) / rule.Rate * float64(time.Second))
+	return true, int(bucket.tokens), untilFull
+}
+
+// Периодически удаляем ведра клиентов, которые давно не приходили
+func (rl *RateLimiter) evictLoop() {
+	ticker := time.NewTicker(rl.idleTTL / 2)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		rl.mutex.Lock()
+		for key, bucket := range rl.buckets {
+			if time.Since(bucket.lastSeen) > rl.idleTTL {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.mutex.Unlock()
+	}
+}
+
+// Ключ клиента: известный API-ключ из заголовка X-API-Key или IP-адрес.
+// Незнакомый ключ не учитывается: иначе клиент получал бы новое ведро,
+// просто меняя значение заголовка.
+func (rl *RateLimiter) clientKey(r *http.Request) string {
+	if apiKey := r.Header.Get("X-API-Key"); rl.apiKeys[apiKey] {
+		return "key:" + apiKey
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// Округляем длительность вверх до целых секунд для заголовков
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// Middleware для ограничения частоты запросов. route - шаблон, под которым
+// обработчик зарегистрирован в маршрутизаторе ("/" ловит все пути): по нему
+// выбирается правило и ведро, поэтому новые пути не дают новых ведер.
+func (rl *RateLimiter) Middleware(route string, next http.HandlerFunc) http.HandlerFunc {
+	rule := rl.ruleFor(route)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed, remaining, reset := rl.allow(rl.clientKey(r)+"|"+route, rule)
+
+		window := ceilSeconds(time.Duration(float64(rule.Burst) / rule.Rate * float64(time.Second)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rule.Burst, window))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(rule.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+
+		if !allowed {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(reset)))
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			response := Response{
+				Message:   "Слишком много запросов, попробуйте позже",
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    "error",
+			}
+
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// Разбираем правила вида "/=10:20,/slow=1:2" (маршрут=запросов_в_секунду:всплеск)
+func parseRateLimitRules(spec string) (map[string]RateLimitRule, error) {
+	rules := make(map[string]RateLimitRule)
+	if strings.TrimSpace(spec) == "" {
+		return rules, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		route, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("правило %q: ожидается формат маршрут=скорость:всплеск", part)
+		}
+
+		rateStr, burstStr, ok := strings.Cut(value, ":")
+		if !ok {
+			return nil, fmt.Errorf("правило %q: ожидается формат маршрут=скорость:всплеск", part)
+		}
+
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("правило %q: неверная скорость %q", part, rateStr)
+		}
+
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("правило %q: неверный всплеск %q", part, burstStr)
+		}
+
+		rules[route] = RateLimitRule{Rate: rate, Burst: burst}
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+)
+
+// Почему тело ответа прочитано не полностью
+const (
+	TruncatedNone    = ""         // Прочитано полностью
+	TruncatedMaxBody = "max-body" // Достигнут предел -max-body
+	TruncatedHead    = "head"     // Нужные метаданные найдены в <head>, дальше не читали
+)
+
+// Читатель, который отдает не больше limit байт и запоминает, что тело было длиннее
+type limitReader struct {
+	r    io.Reader
+	left int64 // Сколько байт еще можно отдать
+	hit  bool  // Тело оказалось длиннее предела
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.left <= 0 {
+		// Предел исчерпан: проверяем одним байтом, есть ли что-то дальше
+		var one [1]byte
+		if n, _ := l.r.Read(one[:]); n > 0 {
+			l.hit = true
+		}
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	return n, err
+}
+
+// Прочитанная страница
+type pageBody struct {
+	meta      PageMeta
+	bytes     int64  // Сколько байт тела прочитано
+	truncated string // Почему тело прочитано не полностью
+	raw       []byte // Прочитанные байты как есть (только если их просили сохранить)
+}
+
+// Функция для чтения и разбора тела страницы.
+// HTML разбирается потоком, с перекодированием в UTF-8. Тело читается не больше
+// -max-body байт, а если не нужны ссылки - только до </head>. JSON, XML и текст
+// читаются целиком, двоичные ответы не читаются вовсе.
+// keep - сохранить прочитанные байты (например, для кеша).
+func readPage(r io.Reader, contentType string, contentLength int64, base *url.URL, keep bool) (pageBody, error) {
+	limited := &limitReader{r: r, left: options.MaxBody}
+	if options.MaxBody <= 0 {
+		limited.left = math.MaxInt64
+	}
+	body := &countingReader{r: limited}
+
+	var src io.Reader = body
+	var raw bytes.Buffer
+	if keep {
+		src = io.TeeReader(body, &raw)
+	}
+
+	buffered := bufio.NewReader(src)
+	charset, charsetFrom := detectCharset(buffered, contentType)
+	kind := detectContent(contentType, buffered)
+
+	var meta PageMeta
+	var stoppedEarly bool
+	switch kind {
+	case ContentHTML:
+		var err error
+		meta, stoppedEarly, err = extractMeta(newCharsetReader(buffered, charset), base, !options.FullBody)
+		if err != nil {
+			return pageBody{}, err
+		}
+	case ContentOther:
+		// Картинки и прочие двоичные данные не разбираем и дальше не читаем
+		stoppedEarly = true
+	default:
+		// JSON, XML и текст читаем целиком: заголовок может быть в любом месте
+		text, err := io.ReadAll(newCharsetReader(buffered, charset))
+		if err != nil {
+			return pageBody{}, err
+		}
+		meta.Title = documentTitle(kind, string(text))
+	}
+	meta.Charset = charset
+	meta.CharsetFrom = charsetFrom
+	meta.ContentType = kind
+	if meta.Title == "" {
+		meta.Title = "Заголовок не найден"
+	}
+
+	page := pageBody{meta: meta, bytes: body.n, raw: raw.Bytes()}
+	if limited.hit {
+		page.truncated = TruncatedMaxBody
+	} else if stoppedEarly && (contentLength < 0 || body.n < contentLength) {
+		page.truncated = TruncatedHead
+	}
+	return page, nil
+}
+
+// Функция для вывода сводки по прочитанным телам ответов за запуск
+func printBodySummary(results []ParseResult) {
+	var read, saved int64
+	truncated := make(map[string]int)
+	unknownSize := 0
+	for _, result := range results {
+		read += result.Bytes
+		if result.Truncated == TruncatedNone {
+			continue
+		}
+		truncated[result.Truncated]++
+		if result.BodySize >= 0 {
+			saved += result.BodySize - result.Bytes
+		} else {
+			unknownSize++
+		}
+	}
+
+	if len(truncated) == 0 {
+		return
+	}
+
+	logf("📦 Прочитано: %s, сэкономлено: %s (остановлено после <head>: %d, обрезано по -max-body: %d",
+		formatBytes(read), formatBytes(saved), truncated[TruncatedHead], truncated[TruncatedMaxBody])
+	if unknownSize > 0 {
+		logf(", размер неизвестен у %d - для них экономия не учтена", unknownSize)
+	}
+	logf(")\n")
+}
+
+// Функция для вывода размера в удобных единицах
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d Б", n)
+	}
+	value := float64(n) / unit
+	for _, suffix := range []string{"КБ", "МБ"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f ГБ", value)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Откуда взята страница при включенном кеше
+const (
+	CacheHit         = "hit"         // Из кеша без запроса: запись моложе -cache-max-age
+	CacheRevalidated = "revalidated" // Сервер ответил 304 Not Modified - взяли из кеша
+	CacheMiss        = "miss"        // Скачали заново
+)
+
+// Запись кеша: ответ сервера и валидаторы для условного запроса
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	FinalURL     string    `json:"final_url"`
+	StatusCode   int       `json:"status_code"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+	Complete     bool      `json:"complete"` // Тело сохранено целиком, а не только до </head>
+	Body         []byte    `json:"body"`
+}
+
+// Дисковый кеш страниц: по файлу на URL в каталоге dir
+type HTTPCache struct {
+	dir    string
+	maxAge time.Duration // Сколько запись считается свежей и используется без запроса
+	bypass bool          // Не использовать сохраненные записи, но сохранять новые
+
+	hits        atomic.Int64
+	revalidated atomic.Int64
+	misses      atomic.Int64
+}
+
+// Создаем кеш в каталоге dir (каталог создается, если его нет)
+func NewHTTPCache(dir string, maxAge time.Duration, bypass bool) (*HTTPCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &HTTPCache{dir: dir, maxAge: maxAge, bypass: bypass}, nil
+}
+
+// Путь к файлу записи: имя - хеш URL, чтобы не зависеть от символов в URL
+func (c *HTTPCache) path(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Ищем запись для URL. Неполная запись (только <head>) не подходит,
+// если сейчас нужна вся страница.
+func (c *HTTPCache) Lookup(rawURL string, needFull bool) *cacheEntry {
+	if c.bypass {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.path(rawURL))
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != rawURL {
+		return nil
+	}
+	if needFull && !entry.Complete {
+		return nil
+	}
+	return &entry
+}
+
+// Свежая ли запись: можно использовать без запроса к серверу
+func (c *HTTPCache) Fresh(entry *cacheEntry) bool {
+	return time.Since(entry.StoredAt) < c.maxAge
+}
+
+// Добавляем к запросу заголовки условного запроса
+func (c *HTTPCache) Revalidate(req *http.Request, entry *cacheEntry) {
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+// Сохраняем запись. Файл пишется во временный и переименовывается,
+// чтобы параллельные воркеры и прерванный запуск не оставили половину записи.
+func (c *HTTPCache) Store(entry *cacheEntry) error {
+	entry.StoredAt = time.Now()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(c.path(entry.URL), data)
+}
+
+// Функция для записи файла целиком: данные пишутся во временный файл рядом и
+// переименовываются. Переименование атомарно, поэтому читатель видит либо старый
+// файл, либо новый, но не половину записи.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Можно ли сохранять ответ: сервер не запретил это через Cache-Control: no-store
+func cacheable(resp *http.Response) bool {
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return false
+		}
+	}
+	return true
+}
+
+// Учитываем, откуда взята страница
+func (c *HTTPCache) count(source string) {
+	switch source {
+	case CacheHit:
+		c.hits.Add(1)
+	case CacheRevalidated:
+		c.revalidated.Add(1)
+	case CacheMiss:
+		c.misses.Add(1)
+	}
+}
+
+// Функция для вывода статистики кеша
+func printCacheStats(c *HTTPCache) {
+	if c == nil {
+		return
+	}
+	hits, revalidated, misses := c.hits.Load(), c.revalidated.Load(), c.misses.Load()
+	total := hits + revalidated + misses
+	if total == 0 {
+		return
+	}
+	logf("💾 Кеш: из кеша %d, подтверждено 304: %d, скачано заново: %d (попаданий %.0f%%)\n",
+		hits, revalidated, misses, float64(hits+revalidated)/float64(total)*100)
+}
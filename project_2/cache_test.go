@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Сервер страницы с валидаторами: запоминает условные заголовки запросов
+type revalidatingServer struct {
+	mutex    sync.Mutex
+	version  int
+	requests int
+	lastETag string
+	lastDate string
+}
+
+func (s *revalidatingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests++
+	s.lastETag = r.Header.Get("If-None-Match")
+	s.lastDate = r.Header.Get("If-Modified-Since")
+
+	etag := fmt.Sprintf(`"v%d"`, s.version)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+	if s.lastETag == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<html><head><title>Версия %d</title></head><body></body></html>", s.version)
+}
+
+// Функция для запроса страницы с проверкой источника ответа
+func fetchCached(t *testing.T, url, wantSource, wantTitle string) {
+	t.Helper()
+
+	result := parsePageTitle(context.Background(), url)
+	if result.Status != StatusOK {
+		t.Fatalf("статус %s: %v", result.Status, result.Error)
+	}
+	if result.Cache != wantSource || result.Title != wantTitle {
+		t.Errorf("источник %q, заголовок %q; want %q, %q", result.Cache, result.Title, wantSource, wantTitle)
+	}
+}
+
+func TestCacheRevalidation(t *testing.T) {
+	defer func(saved *HTTPCache) { pageCache = saved }(pageCache)
+
+	page := &revalidatingServer{version: 1}
+	server := httptest.NewServer(page)
+	defer server.Close()
+
+	cache, err := NewHTTPCache(t.TempDir(), 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageCache = cache
+
+	// Первый запрос - без условий, ответ сохраняется в кеш
+	fetchCached(t, server.URL, CacheMiss, "Версия 1")
+	if page.lastETag != "" || page.lastDate != "" {
+		t.Errorf("первый запрос с условиями: If-None-Match %q, If-Modified-Since %q", page.lastETag, page.lastDate)
+	}
+
+	// Запись устарела (-cache-max-age 0): условный запрос, 304 - страница из кеша
+	fetchCached(t, server.URL, CacheRevalidated, "Версия 1")
+	if page.lastETag != `"v1"` || page.lastDate != "Mon, 01 Jan 2024 00:00:00 GMT" {
+		t.Errorf("условный запрос: If-None-Match %q, If-Modified-Since %q", page.lastETag, page.lastDate)
+	}
+
+	// Страница изменилась: сервер отвечает 200, кеш обновляется
+	page.mutex.Lock()
+	page.version = 2
+	page.mutex.Unlock()
+	fetchCached(t, server.URL, CacheMiss, "Версия 2")
+	fetchCached(t, server.URL, CacheRevalidated, "Версия 2")
+
+	if hits, revalidated, misses := cache.hits.Load(), cache.revalidated.Load(), cache.misses.Load(); hits != 0 || revalidated != 2 || misses != 2 {
+		t.Errorf("статистика: hits %d, revalidated %d, misses %d", hits, revalidated, misses)
+	}
+}
+
+func TestCacheFreshEntry(t *testing.T) {
+	defer func(saved *HTTPCache) { pageCache = saved }(pageCache)
+
+	page := &revalidatingServer{version: 1}
+	server := httptest.NewServer(page)
+	defer server.Close()
+
+	cache, err := NewHTTPCache(t.TempDir(), time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageCache = cache
+
+	fetchCached(t, server.URL, CacheMiss, "Версия 1")
+	fetchCached(t, server.URL, CacheHit, "Версия 1")
+	if page.requests != 1 {
+		t.Errorf("запросов к серверу %d, want 1 (свежая запись берется без запроса)", page.requests)
+	}
+
+	// -cache-bypass: сохраненную запись не используем, но обновляем
+	bypass, err := NewHTTPCache(cache.dir, time.Hour, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageCache = bypass
+	fetchCached(t, server.URL, CacheMiss, "Версия 1")
+	if page.lastETag != "" {
+		t.Errorf("при -cache-bypass отправлен If-None-Match %q", page.lastETag)
+	}
+}
+
+func TestCacheNoStore(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", true},
+		{"max-age=60", true},
+		{"no-store", false},
+		{"private, No-Store", false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Cache-Control", tt.header)
+		if got := cacheable(resp); got != tt.want {
+			t.Errorf("cacheable(Cache-Control: %q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
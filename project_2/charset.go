@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// Сколько байт начала документа просматриваем в поисках <meta charset> и для угадывания
+const charsetSniffSize = 1024
+
+// Откуда определена кодировка страницы
+const (
+	CharsetFromBOM     = "bom"     // Метка порядка байт UTF-8
+	CharsetFromHeader  = "header"  // Content-Type: text/html; charset=...
+	CharsetFromMeta    = "meta"    // <meta charset>, <meta http-equiv="Content-Type"> или <?xml encoding>
+	CharsetFromSniff   = "sniff"   // Угадана по байтам документа
+	CharsetFromDefault = "default" // Ничего не нашли - считаем UTF-8
+)
+
+// Названия кодировок и их синонимы (как в заголовках и meta-тегах)
+var charsetAliases = map[string]string{
+	"utf-8":             "utf-8",
+	"utf8":              "utf-8",
+	"unicode-1-1-utf-8": "utf-8",
+	"windows-1251":      "windows-1251",
+	"cp1251":            "windows-1251",
+	"x-cp1251":          "windows-1251",
+	"koi8-r":            "koi8-r",
+	"koi8r":             "koi8-r",
+	"koi8":              "koi8-r",
+	"cskoi8r":           "koi8-r",
+	"windows-1252":      "windows-1252",
+	"cp1252":            "windows-1252",
+	"x-cp1252":          "windows-1252",
+	"iso-8859-1":        "windows-1252",
+	"iso8859-1":         "windows-1252",
+	"latin1":            "windows-1252",
+	"l1":                "windows-1252",
+	"us-ascii":          "windows-1252",
+	"ascii":             "windows-1252",
+}
+
+// Однобайтовые кодировки: символы 0x80-0xFF задаются таблицей, 0x00-0x7F совпадают с ASCII
+var singleByteCharsets = map[string]*[128]rune{
+	"windows-1251": &windows1251Table,
+	"koi8-r":       &koi8rTable,
+	"windows-1252": &windows1252Table,
+}
+
+// Функция для приведения названия кодировки к каноническому ("CP1251" -> "windows-1251")
+func normalizeCharset(label string) (string, bool) {
+	label = strings.ToLower(strings.Trim(strings.TrimSpace(label), `"'`))
+	charset, ok := charsetAliases[label]
+	return charset, ok
+}
+
+// Функция для определения кодировки страницы.
+// Порядок как у браузеров: BOM, заголовок Content-Type, <meta> в начале документа, угадывание по байтам.
+// Байты из br не поглощаются (кроме BOM), поэтому после определения документ читается с начала.
+func detectCharset(br *bufio.Reader, contentType string) (string, string) {
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+		return "utf-8", CharsetFromBOM
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if charset, ok := normalizeCharset(params["charset"]); ok {
+			return charset, CharsetFromHeader
+		}
+	}
+
+	// Peek вернет меньше байт, если документ короче - это не ошибка
+	head, _ := br.Peek(charsetSniffSize)
+
+	if charset, ok := xmlDeclCharset(head); ok {
+		return charset, CharsetFromMeta
+	}
+	if charset, ok := metaCharset(head); ok {
+		return charset, CharsetFromMeta
+	}
+	if charset, ok := sniffCharset(head); ok {
+		return charset, CharsetFromSniff
+	}
+	return "utf-8", CharsetFromDefault
+}
+
+// Функция для поиска кодировки в <meta charset="..."> или
+// <meta http-equiv="Content-Type" content="text/html; charset=...">
+func metaCharset(head []byte) (string, bool) {
+	tokenizer := newHTMLTokenizer(bytes.NewReader(head))
+	for {
+		token, err := tokenizer.Next()
+		if err != nil {
+			return "", false
+		}
+
+		switch {
+		case token.Type == startTagToken && token.Name == "meta":
+			if value, ok := token.Attr("charset"); ok {
+				if charset, ok := normalizeCharset(value); ok {
+					return charset, true
+				}
+			}
+			if httpEquiv, _ := token.Attr("http-equiv"); strings.EqualFold(httpEquiv, "content-type") {
+				content, _ := token.Attr("content")
+				if _, params, err := mime.ParseMediaType(content); err == nil {
+					if charset, ok := normalizeCharset(params["charset"]); ok {
+						return charset, true
+					}
+				}
+			}
+		case token.Type == startTagToken && token.Name == "body",
+			token.Type == endTagToken && token.Name == "head":
+			// Кодировку объявляют только в <head>
+			return "", false
+		}
+	}
+}
+
+// Функция для угадывания кодировки по байтам: корректный UTF-8 считаем UTF-8,
+// иначе выбираем кириллическую кодировку, в которой получается больше строчных русских букв
+func sniffCharset(head []byte) (string, bool) {
+	// Последний символ мог обрезаться на границе просмотра - отбрасываем его
+	trimmed := head
+	if len(head) == charsetSniffSize {
+		for i := 1; i < utf8.UTFMax && i <= len(trimmed); i++ {
+			if utf8.RuneStart(trimmed[len(trimmed)-i]) {
+				if !utf8.FullRune(trimmed[len(trimmed)-i:]) {
+					trimmed = trimmed[:len(trimmed)-i]
+				}
+				break
+			}
+		}
+	}
+
+	if utf8.Valid(trimmed) {
+		if hasHighBytes(trimmed) {
+			return "utf-8", true
+		}
+		// Чистый ASCII ничего не говорит о кодировке
+		return "", false
+	}
+
+	score1251 := cyrillicScore(head, &windows1251Table)
+	scoreKOI8 := cyrillicScore(head, &koi8rTable)
+	switch {
+	case score1251 == 0 && scoreKOI8 == 0:
+		return "windows-1252", true
+	case scoreKOI8 > score1251:
+		return "koi8-r", true
+	default:
+		return "windows-1251", true
+	}
+}
+
+// Есть ли в данных байты за пределами ASCII
+func hasHighBytes(data []byte) bool {
+	for _, b := range data {
+		if b >= 0x80 {
+			return true
+		}
+	}
+	return false
+}
+
+// Сколько строчных русских букв получается, если раскодировать данные таблицей.
+// Обычный текст в основном из строчных букв, а чужая кодировка превращает их в прописные.
+func cyrillicScore(data []byte, table *[128]rune) int {
+	score := 0
+	for _, b := range data {
+		if b < 0x80 {
+			continue
+		}
+		if r := table[b-0x80]; (r >= 'а' && r <= 'я') || r == 'ё' {
+			score++
+		}
+	}
+	return score
+}
+
+// Читатель, который на лету перекодирует однобайтовую кодировку в UTF-8
+type charsetReader struct {
+	r       io.Reader
+	table   *[128]rune
+	src     []byte // Буфер для исходных байт
+	pending []byte // Перекодированные байты, которые еще не отдали
+	err     error  // Ошибка исходного читателя (отдаем после pending)
+}
+
+// Функция для создания читателя, отдающего документ в UTF-8
+func newCharsetReader(r io.Reader, charset string) io.Reader {
+	table, ok := singleByteCharsets[charset]
+	if !ok {
+		return r
+	}
+	return &charsetReader{r: r, table: table, src: make([]byte, 4*1024)}
+}
+
+func (c *charsetReader) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+
+		var n int
+		n, c.err = c.r.Read(c.src)
+		for _, b := range c.src[:n] {
+			if b < 0x80 {
+				c.pending = append(c.pending, b)
+			} else {
+				c.pending = utf8.AppendRune(c.pending, c.table[b-0x80])
+			}
+		}
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Функция для перекодирования UTF-8 строки в однобайтовую кодировку
+// (символы, которых нет в кодировке, заменяются на '?')
+func encodeCharset(s, charset string) []byte {
+	table, ok := singleByteCharsets[charset]
+	if !ok {
+		return []byte(s)
+	}
+
+	reverse := make(map[rune]byte, len(table))
+	for i, r := range table {
+		reverse[r] = byte(0x80 + i)
+	}
+
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch b, ok := reverse[r]; {
+		case r < 0x80:
+			out = append(out, byte(r))
+		case ok:
+			out = append(out, b)
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// windows-1251: кириллица Windows
+var windows1251Table = [128]rune{
+	0x0402, 0x0403, 0x201A, 0x0453, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x20AC, 0x2030, 0x0409, 0x2039, 0x040A, 0x040C, 0x040B, 0x040F,
+	0x0452, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x0098, 0x2122, 0x0459, 0x203A, 0x045A, 0x045C, 0x045B, 0x045F,
+	0x00A0, 0x040E, 0x045E, 0x0408, 0x00A4, 0x0490, 0x00A6, 0x00A7,
+	0x0401, 0x00A9, 0x0404, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x0407,
+	0x00B0, 0x00B1, 0x0406, 0x0456, 0x0491, 0x00B5, 0x00B6, 0x00B7,
+	0x0451, 0x2116, 0x0454, 0x00BB, 0x0458, 0x0405, 0x0455, 0x0457,
+	0x0410, 0x0411, 0x0412, 0x0413, 0x0414, 0x0415, 0x0416, 0x0417,
+	0x0418, 0x0419, 0x041A, 0x041B, 0x041C, 0x041D, 0x041E, 0x041F,
+	0x0420, 0x0421, 0x0422, 0x0423, 0x0424, 0x0425, 0x0426, 0x0427,
+	0x0428, 0x0429, 0x042A, 0x042B, 0x042C, 0x042D, 0x042E, 0x042F,
+	0x0430, 0x0431, 0x0432, 0x0433, 0x0434, 0x0435, 0x0436, 0x0437,
+	0x0438, 0x0439, 0x043A, 0x043B, 0x043C, 0x043D, 0x043E, 0x043F,
+	0x0440, 0x0441, 0x0442, 0x0443, 0x0444, 0x0445, 0x0446, 0x0447,
+	0x0448, 0x0449, 0x044A, 0x044B, 0x044C, 0x044D, 0x044E, 0x044F,
+}
+
+// KOI8-R: кириллица Unix
+var koi8rTable = [128]rune{
+	0x2500, 0x2502, 0x250C, 0x2510, 0x2514, 0x2518, 0x251C, 0x2524,
+	0x252C, 0x2534, 0x253C, 0x2580, 0x2584, 0x2588, 0x258C, 0x2590,
+	0x2591, 0x2592, 0x2593, 0x2320, 0x25A0, 0x2219, 0x221A, 0x2248,
+	0x2264, 0x2265, 0x00A0, 0x2321, 0x00B0, 0x00B2, 0x00B7, 0x00F7,
+	0x2550, 0x2551, 0x2552, 0x0451, 0x2553, 0x2554, 0x2555, 0x2556,
+	0x2557, 0x2558, 0x2559, 0x255A, 0x255B, 0x255C, 0x255D, 0x255E,
+	0x255F, 0x2560, 0x2561, 0x0401, 0x2562, 0x2563, 0x2564, 0x2565,
+	0x2566, 0x2567, 0x2568, 0x2569, 0x256A, 0x256B, 0x256C, 0x00A9,
+	0x044E, 0x0430, 0x0431, 0x0446, 0x0434, 0x0435, 0x0444, 0x0433,
+	0x0445, 0x0438, 0x0439, 0x043A, 0x043B, 0x043C, 0x043D, 0x043E,
+	0x043F, 0x044F, 0x0440, 0x0441, 0x0442, 0x0443, 0x0436, 0x0432,
+	0x044C, 0x044B, 0x0437, 0x0448, 0x044D, 0x0449, 0x0447, 0x044A,
+	0x042E, 0x0410, 0x0411, 0x0426, 0x0414, 0x0415, 0x0424, 0x0413,
+	0x0425, 0x0418, 0x0419, 0x041A, 0x041B, 0x041C, 0x041D, 0x041E,
+	0x041F, 0x042F, 0x0420, 0x0421, 0x0422, 0x0423, 0x0416, 0x0412,
+	0x042C, 0x042B, 0x0417, 0x0428, 0x042D, 0x0429, 0x0427, 0x042A,
+}
+
+// windows-1252: западноевропейская (ей же в HTML считаются iso-8859-1 и us-ascii)
+var windows1252Table = [128]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+	0x00A0, 0x00A1, 0x00A2, 0x00A3, 0x00A4, 0x00A5, 0x00A6, 0x00A7,
+	0x00A8, 0x00A9, 0x00AA, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x00AF,
+	0x00B0, 0x00B1, 0x00B2, 0x00B3, 0x00B4, 0x00B5, 0x00B6, 0x00B7,
+	0x00B8, 0x00B9, 0x00BA, 0x00BB, 0x00BC, 0x00BD, 0x00BE, 0x00BF,
+	0x00C0, 0x00C1, 0x00C2, 0x00C3, 0x00C4, 0x00C5, 0x00C6, 0x00C7,
+	0x00C8, 0x00C9, 0x00CA, 0x00CB, 0x00CC, 0x00CD, 0x00CE, 0x00CF,
+	0x00D0, 0x00D1, 0x00D2, 0x00D3, 0x00D4, 0x00D5, 0x00D6, 0x00D7,
+	0x00D8, 0x00D9, 0x00DA, 0x00DB, 0x00DC, 0x00DD, 0x00DE, 0x00DF,
+	0x00E0, 0x00E1, 0x00E2, 0x00E3, 0x00E4, 0x00E5, 0x00E6, 0x00E7,
+	0x00E8, 0x00E9, 0x00EA, 0x00EB, 0x00EC, 0x00ED, 0x00EE, 0x00EF,
+	0x00F0, 0x00F1, 0x00F2, 0x00F3, 0x00F4, 0x00F5, 0x00F6, 0x00F7,
+	0x00F8, 0x00F9, 0x00FA, 0x00FB, 0x00FC, 0x00FD, 0x00FE, 0x00FF,
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestDetectCharset(t *testing.T) {
+	const russian = "<p>Съешь же ещё этих мягких французских булок, да выпей чаю</p>"
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		charset     string
+		from        string
+	}{
+		{
+			name:    "BOM",
+			body:    append([]byte{0xEF, 0xBB, 0xBF}, "<p>текст</p>"...),
+			charset: "utf-8",
+			from:    CharsetFromBOM,
+		},
+		{
+			name:        "заголовок важнее meta",
+			contentType: "text/html; charset=CP1251",
+			body:        []byte(`<meta charset="koi8-r">`),
+			charset:     "windows-1251",
+			from:        CharsetFromHeader,
+		},
+		{
+			name:    "meta charset",
+			body:    []byte(`<html><head><meta charset="KOI8-R"></head>`),
+			charset: "koi8-r",
+			from:    CharsetFromMeta,
+		},
+		{
+			name:    "meta http-equiv",
+			body:    []byte(`<meta http-equiv="Content-Type" content="text/html; charset=windows-1251">`),
+			charset: "windows-1251",
+			from:    CharsetFromMeta,
+		},
+		{
+			name:    "meta после <body> не считается",
+			body:    []byte(`<body><meta charset="koi8-r">hello`),
+			charset: "utf-8",
+			from:    CharsetFromDefault,
+		},
+		{
+			name:    "XML-объявление",
+			body:    []byte(`<?xml version="1.0" encoding="windows-1251"?><rss/>`),
+			charset: "windows-1251",
+			from:    CharsetFromMeta,
+		},
+		{
+			name:    "угадывание UTF-8",
+			body:    []byte(russian),
+			charset: "utf-8",
+			from:    CharsetFromSniff,
+		},
+		{
+			name:    "угадывание windows-1251",
+			body:    encodeCharset(russian, "windows-1251"),
+			charset: "windows-1251",
+			from:    CharsetFromSniff,
+		},
+		{
+			name:    "угадывание koi8-r",
+			body:    encodeCharset(russian, "koi8-r"),
+			charset: "koi8-r",
+			from:    CharsetFromSniff,
+		},
+		{
+			name:    "чистый ASCII",
+			body:    []byte("<p>hello</p>"),
+			charset: "utf-8",
+			from:    CharsetFromDefault,
+		},
+		{
+			name:        "неизвестная кодировка в заголовке игнорируется",
+			contentType: "text/html; charset=x-unknown",
+			body:        []byte(`<meta charset="koi8-r">`),
+			charset:     "koi8-r",
+			from:        CharsetFromMeta,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charset, from := detectCharset(bufio.NewReader(bytes.NewReader(tt.body)), tt.contentType)
+			if charset != tt.charset || from != tt.from {
+				t.Errorf("detectCharset = (%q, %q), want (%q, %q)", charset, from, tt.charset, tt.from)
+			}
+		})
+	}
+}
+
+func TestCharsetReaderRoundTrip(t *testing.T) {
+	const text = "Привет, мир! Ёлка"
+
+	for _, charset := range []string{"utf-8", "windows-1251", "koi8-r"} {
+		t.Run(charset, func(t *testing.T) {
+			decoded, err := io.ReadAll(newCharsetReader(bytes.NewReader(encodeCharset(text, charset)), charset))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(decoded) != text {
+				t.Errorf("got %q, want %q", decoded, text)
+			}
+		})
+	}
+}
+
+func TestNormalizeCharset(t *testing.T) {
+	tests := map[string]string{
+		"UTF8":       "utf-8",
+		" 'cp1251' ": "windows-1251",
+		"KOI8":       "koi8-r",
+		"latin1":     "windows-1252",
+	}
+	for label, want := range tests {
+		if got, ok := normalizeCharset(label); !ok || got != want {
+			t.Errorf("normalizeCharset(%q) = (%q, %v), want %q", label, got, ok, want)
+		}
+	}
+	if _, ok := normalizeCharset("x-unknown"); ok {
+		t.Error("неизвестная кодировка не должна распознаваться")
+	}
+}
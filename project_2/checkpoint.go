@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Версия формата контрольной точки: файл другой версии не загружается
+const checkpointVersion = 1
+
+// Контрольная точка обхода: все, что нужно, чтобы продолжить его после сбоя или Ctrl-C
+type crawlCheckpoint struct {
+	Version  int            `json:"version"`
+	SavedAt  time.Time      `json:"saved_at"`
+	Seeds    []string       `json:"seeds"`              // Стартовые URL (нормализованные)
+	MaxDepth int            `json:"max_depth"`          // Глубина обхода
+	Depth    int            `json:"depth"`              // Текущий уровень
+	Pending  []string       `json:"pending"`            // URL текущего уровня, которые еще не обработаны
+	Next     []string       `json:"next"`               // Уже найденные URL следующего уровня
+	Visited  []string       `json:"visited"`            // Все URL, попавшие в обход
+	Attempts map[string]int `json:"attempts,omitempty"` // Попытки, потраченные на прерванные URL
+	Results  []savedResult  `json:"results"`            // Готовые результаты по порядку
+}
+
+// Результат в контрольной точке: ошибка хранится текстом (ее класс - в ErrorClass)
+type savedResult struct {
+	ParseResult
+	Error string `json:",omitempty"`
+}
+
+// Функция для загрузки контрольной точки (nil - файла нет, обход начинается заново)
+func loadCheckpoint(path string) (*crawlCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint crawlCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if checkpoint.Version != checkpointVersion {
+		return nil, fmt.Errorf("%s: версия %d, ожидается %d", path, checkpoint.Version, checkpointVersion)
+	}
+	return &checkpoint, nil
+}
+
+// Проверяем, что контрольная точка от того же обхода: те же стартовые URL и глубина.
+// Бюджет -max-pages можно менять, например, увеличить при продолжении.
+func (c *crawlCheckpoint) matches(seeds []string, crawlOpts CrawlOptions) error {
+	normalized := normalizeSeeds(seeds)
+	saved := append([]string(nil), c.Seeds...)
+	sort.Strings(normalized)
+	sort.Strings(saved)
+	if fmt.Sprint(normalized) != fmt.Sprint(saved) {
+		return fmt.Errorf("контрольная точка от обхода с другими стартовыми URL: %v", c.Seeds)
+	}
+	if c.MaxDepth != crawlOpts.MaxDepth {
+		return fmt.Errorf("контрольная точка от обхода с глубиной %d, а указана %d", c.MaxDepth, crawlOpts.MaxDepth)
+	}
+	return nil
+}
+
+// Функция для нормализации стартовых URL без дубликатов
+func normalizeSeeds(seeds []string) []string {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, seed := range seeds {
+		u, err := normalizeURL(seed)
+		if err == nil && !seen[u] {
+			seen[u] = true
+			normalized = append(normalized, u)
+		}
+	}
+	return normalized
+}
+
+// Состояние обхода. Воркеры записывают в него результаты, а запись контрольной
+// точки делает под мьютексом только копию - сериализация и диск идут без блокировки.
+type crawlState struct {
+	mutex    sync.Mutex
+	seeds    []string
+	maxDepth int
+	depth    int                    // Текущий уровень
+	level    []string               // URL текущего уровня по порядку
+	done     map[string]ParseResult // Готовые результаты текущего уровня
+	next     []string               // Найденные URL следующего уровня
+	results  []ParseResult          // Результаты предыдущих уровней
+	attempts map[string]int         // Потраченные попытки URL, прерванных на середине
+	visited  *VisitedSet
+	changed  bool // Есть изменения после последней контрольной точки
+}
+
+// Функция для создания состояния обхода: с нуля или из контрольной точки
+func newCrawlState(seeds []string, crawlOpts CrawlOptions, checkpoint *crawlCheckpoint) *crawlState {
+	state := &crawlState{
+		seeds:    normalizeSeeds(seeds),
+		maxDepth: crawlOpts.MaxDepth,
+		done:     make(map[string]ParseResult),
+		attempts: make(map[string]int),
+		visited:  NewVisitedSet(crawlOpts.MaxPages),
+	}
+
+	if checkpoint == nil {
+		for _, seed := range state.seeds {
+			if state.visited.TryAdd(seed) {
+				state.level = append(state.level, seed)
+			}
+		}
+		return state
+	}
+
+	// Посещенные восстанавливаем без учета бюджета: они уже были в обходе
+	for _, u := range checkpoint.Visited {
+		state.visited.restore(u)
+	}
+	state.depth = checkpoint.Depth
+	state.next = checkpoint.Next
+	for u, n := range checkpoint.Attempts {
+		state.attempts[u] = n
+	}
+	for _, saved := range checkpoint.Results {
+		result := saved.ParseResult
+		if saved.Error != "" {
+			result.Error = errors.New(saved.Error)
+		}
+		if result.Depth < state.depth {
+			state.results = append(state.results, result)
+		} else {
+			state.level = append(state.level, result.URL)
+			state.done[result.URL] = result
+		}
+	}
+	state.level = append(state.level, checkpoint.Pending...)
+	return state
+}
+
+// URL текущего уровня, которые еще предстоит обработать
+func (s *crawlState) pending() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var pending []string
+	for _, u := range s.level {
+		if _, ok := s.done[u]; !ok {
+			pending = append(pending, u)
+		}
+	}
+	return pending
+}
+
+// Сколько попыток URL потрачено в прошлом запуске
+func (s *crawlState) priorAttempts(u string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.attempts[u]
+}
+
+// Записываем результат URL вместе с найденными на странице ссылками. Все под одним
+// мьютексом: в контрольную точку не попадет страница без своих ссылок или наоборот.
+// Отмененный URL остается в очереди, запоминаем только потраченные попытки
+// (прерванная попытка не считается).
+// Если редирект привел на уже посещенный URL (/moved/5 -> /page/5), страница
+// отмечается дубликатом: в результаты обхода она не попадает, ее ссылки не обходятся.
+func (s *crawlState) complete(u string, result ParseResult, links []string) ParseResult {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.changed = true
+	if result.Status == StatusCancelled {
+		if result.Attempts > 1 {
+			s.attempts[u] = result.Attempts - 1
+		}
+		return result
+	}
+
+	if result.FinalURL != "" {
+		if final, err := normalizeURL(result.FinalURL); err == nil && final != u && !s.visited.Mark(final) {
+			result.duplicate = true
+			links = nil
+		}
+	}
+	for _, link := range links {
+		if s.visited.TryAdd(link) {
+			s.next = append(s.next, link)
+		}
+	}
+	s.done[u] = result
+	delete(s.attempts, u)
+	return result
+}
+
+// Завершаем уровень: результаты по порядку уровня (необработанные - из results пула)
+// и переход к следующему уровню. false - уровень обработан не весь (запуск отменен).
+func (s *crawlState) finishLevel(poolResults []ParseResult) ([]ParseResult, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	fromPool := make(map[string]ParseResult, len(poolResults))
+	for _, result := range poolResults {
+		fromPool[result.URL] = result
+	}
+
+	levelResults := make([]ParseResult, 0, len(s.level))
+	for _, u := range s.level {
+		if result, ok := s.done[u]; ok {
+			if !result.duplicate {
+				levelResults = append(levelResults, result)
+			}
+		} else if result, ok := fromPool[u]; ok {
+			levelResults = append(levelResults, result)
+		}
+	}
+
+	// После отмены уровень не завершен: состояние оставляем для контрольной точки
+	if len(s.done) < len(s.level) {
+		return levelResults, false
+	}
+
+	s.results = append(s.results, levelResults...)
+	s.level, s.next = s.next, nil
+	s.done = make(map[string]ParseResult)
+	s.depth++
+	s.changed = true
+	return levelResults, true
+}
+
+// Текущий уровень и его номер
+func (s *crawlState) currentLevel() (int, []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.depth, s.level
+}
+
+// Готовые результаты предыдущих уровней
+func (s *crawlState) previousResults() []ParseResult {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]ParseResult(nil), s.results...)
+}
+
+// Все готовые результаты: предыдущие уровни и готовые URL текущего
+func (s *crawlState) completed() []ParseResult {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	completed := append([]ParseResult(nil), s.results...)
+	for _, u := range s.level {
+		if result, ok := s.done[u]; ok && !result.duplicate {
+			completed = append(completed, result)
+		}
+	}
+	return completed
+}
+
+// Копия состояния для контрольной точки. Под мьютексом только копируются срезы и карта,
+// чтобы воркеры не ждали: сортировка посещенных и подготовка результатов идут после.
+// Результаты копируются по значению: готовый результат воркеры больше не меняют.
+// Второе значение - были ли изменения с прошлого снимка.
+func (s *crawlState) snapshot() (*crawlCheckpoint, bool) {
+	s.mutex.Lock()
+	checkpoint := &crawlCheckpoint{
+		Version:  checkpointVersion,
+		SavedAt:  time.Now(),
+		Seeds:    s.seeds,
+		MaxDepth: s.maxDepth,
+		Depth:    s.depth,
+		Next:     append([]string(nil), s.next...),
+		Visited:  s.visited.Items(),
+		Attempts: make(map[string]int, len(s.attempts)),
+	}
+	for u, n := range s.attempts {
+		checkpoint.Attempts[u] = n
+	}
+	results := make([]ParseResult, 0, len(s.results)+len(s.done))
+	results = append(results, s.results...)
+	for _, u := range s.level {
+		if result, ok := s.done[u]; ok {
+			// Дубликат в контрольную точку не пишется: его URL и так среди посещенных
+			if !result.duplicate {
+				results = append(results, result)
+			}
+		} else {
+			checkpoint.Pending = append(checkpoint.Pending, u)
+		}
+	}
+	changed := s.changed
+	s.changed = false
+	s.mutex.Unlock()
+
+	sort.Strings(checkpoint.Visited)
+	checkpoint.Results = make([]savedResult, 0, len(results))
+	for _, result := range results {
+		checkpoint.Results = append(checkpoint.Results, newSavedResult(result))
+	}
+	return checkpoint, changed
+}
+
+// Результат для контрольной точки
+func newSavedResult(result ParseResult) savedResult {
+	saved := savedResult{ParseResult: result}
+	if result.Error != nil {
+		saved.Error = result.Error.Error()
+	}
+	saved.ParseResult.Error = nil
+	return saved
+}
+
+// Периодическая запись контрольных точек в отдельной горутине
+type Checkpointer struct {
+	path  string
+	every time.Duration
+	state *crawlState
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Функция для запуска периодической записи контрольных точек (nil - путь не задан)
+func startCheckpoints(path string, every time.Duration, state *crawlState) *Checkpointer {
+	if path == "" {
+		return nil
+	}
+
+	c := &Checkpointer{
+		path:  path,
+		every: every,
+		state: state,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go c.loop()
+	return c
+}
+
+// Пишем контрольную точку раз в every, если состояние изменилось
+func (c *Checkpointer) loop() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := c.save(false); err != nil {
+				logf("⚠️  Не удалось сохранить контрольную точку: %v\n", err)
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Сохраняем контрольную точку (force - даже без изменений), возвращаем записанное
+func (c *Checkpointer) save(force bool) (*crawlCheckpoint, error) {
+	checkpoint, changed := c.state.snapshot()
+	if !changed && !force {
+		return checkpoint, nil
+	}
+
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint, writeFileAtomic(c.path, data)
+}
+
+// Останавливаем запись. Обход завершен - контрольная точка больше не нужна и удаляется,
+// иначе сохраняем последнее состояние, чтобы продолжить с него.
+func (c *Checkpointer) Stop(finished bool) {
+	if c == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+
+	if finished {
+		if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logf("⚠️  Не удалось удалить контрольную точку: %v\n", err)
+		}
+		return
+	}
+
+	checkpoint, err := c.save(true)
+	if err != nil {
+		logf("⚠️  Не удалось сохранить контрольную точку: %v\n", err)
+		return
+	}
+	logf("💾 Контрольная точка %s: готово %d, в очереди %d. Продолжить - запустить с тем же -checkpoint\n",
+		c.path, len(checkpoint.Results), len(checkpoint.Pending)+len(checkpoint.Next))
+}
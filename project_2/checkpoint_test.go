@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Тестовый сервер, который считает запросы страниц. Запрос к stopAt отменяет
+// обход (как Ctrl-C) и ждет, пока клиент оборвет соединение.
+type countingFixture struct {
+	mutex    sync.Mutex
+	requests map[string]int
+	handler  http.Handler
+	stopAt   string
+	cancel   context.CancelFunc
+}
+
+func (f *countingFixture) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	f.requests[r.URL.Path]++
+	stop := f.cancel != nil && r.URL.Path == f.stopAt
+	f.mutex.Unlock()
+
+	if stop {
+		f.cancel()
+		<-r.Context().Done()
+		return
+	}
+	f.handler.ServeHTTP(w, r)
+}
+
+// Функция для запуска тестового сервера со счетчиком запросов
+func newCountingFixture(t *testing.T) (*countingFixture, *httptest.Server) {
+	t.Helper()
+
+	opts := defaultFixtureOptions
+	opts.Latency, opts.Jitter = 0, 0
+	fixture := &countingFixture{requests: make(map[string]int), handler: NewFixtureHandler(opts)}
+	server := httptest.NewServer(fixture)
+	t.Cleanup(server.Close)
+	return fixture, server
+}
+
+// URL результатов по алфавиту
+func resultURLs(results []ParseResult) []string {
+	var urls []string
+	for _, result := range results {
+		urls = append(urls, result.URL)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func TestCrawlCheckpointResume(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.Workers = 1
+	options.FullBody = true
+
+	// Обход без прерывания - с ним сравниваем продолженный
+	_, reference := newCountingFixture(t)
+	seeds := []string{reference.URL + "/page/1"}
+	want := resultURLs(crawl(context.Background(), seeds, CrawlOptions{
+		MaxDepth: 1, AllowedDomains: defaultCrawlDomains(seeds),
+	}))
+	if len(want) < 3 {
+		t.Fatalf("в обходе %d страниц, для проверки нужно больше", len(want))
+	}
+	for i := range want {
+		want[i] = strings.TrimPrefix(want[i], reference.URL)
+	}
+
+	fixture, server := newCountingFixture(t)
+	seeds = []string{server.URL + "/page/1"}
+	crawlOpts := CrawlOptions{
+		MaxDepth:        1,
+		AllowedDomains:  defaultCrawlDomains(seeds),
+		Checkpoint:      filepath.Join(t.TempDir(), "crawl.json"),
+		CheckpointEvery: time.Hour,
+	}
+
+	// Первый запуск прерывается на второй странице уровня 1
+	ctx, cancel := context.WithCancel(context.Background())
+	fixture.stopAt, fixture.cancel = "/page/5", cancel
+	crawl(ctx, seeds, crawlOpts)
+	cancel()
+
+	checkpoint, err := loadCheckpoint(crawlOpts.Checkpoint)
+	if err != nil || checkpoint == nil {
+		t.Fatalf("контрольная точка не сохранена: %v", err)
+	}
+	if err := checkpoint.matches(seeds, crawlOpts); err != nil {
+		t.Fatalf("контрольная точка не подходит к тому же обходу: %v", err)
+	}
+	if checkpoint.Depth != 1 || len(checkpoint.Results) != 2 || len(checkpoint.Pending) == 0 {
+		t.Fatalf("контрольная точка: уровень %d, готово %d, в очереди %v",
+			checkpoint.Depth, len(checkpoint.Results), checkpoint.Pending)
+	}
+	if other := (CrawlOptions{MaxDepth: 2}); checkpoint.matches(seeds, other) == nil {
+		t.Error("контрольная точка подошла к обходу с другой глубиной")
+	}
+
+	// Второй запуск продолжает с контрольной точки
+	fixture.mutex.Lock()
+	fixture.cancel = nil
+	fixture.mutex.Unlock()
+	crawlOpts.Resume = checkpoint
+	results := crawl(context.Background(), seeds, crawlOpts)
+
+	got := resultURLs(results)
+	for i := range got {
+		got[i] = strings.TrimPrefix(got[i], server.URL)
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("после продолжения страницы %v, want %v", got, want)
+	}
+	for _, result := range results {
+		if result.Status != StatusOK {
+			t.Errorf("%s: статус %s", result.URL, result.Status)
+		}
+	}
+
+	// Готовые страницы не загружаются повторно; прерванная - загружается еще раз
+	fixture.mutex.Lock()
+	defer fixture.mutex.Unlock()
+	for path, n := range fixture.requests {
+		limit := 1
+		if path == fixture.stopAt {
+			limit = 2
+		}
+		if n > limit {
+			t.Errorf("%s запрошена %d раз", path, n)
+		}
+	}
+
+	// Обход завершен - контрольная точка удалена
+	if checkpoint, _ := loadCheckpoint(crawlOpts.Checkpoint); checkpoint != nil {
+		t.Error("после завершения обхода контрольная точка не удалена")
+	}
+}
+
+func TestCrawlStateSnapshot(t *testing.T) {
+	state := newCrawlState([]string{"http://a/1", "http://a/2"}, CrawlOptions{MaxDepth: 1}, nil)
+
+	state.complete("http://a/1", ParseResult{URL: "http://a/1", Status: StatusOK}, []string{"http://a/3", "http://a/2"})
+	state.complete("http://a/2", ParseResult{URL: "http://a/2", Status: StatusCancelled, Attempts: 3}, nil)
+
+	checkpoint, changed := state.snapshot()
+	if !changed {
+		t.Error("snapshot не заметил изменений")
+	}
+	if len(checkpoint.Results) != 1 || strings.Join(checkpoint.Pending, " ") != "http://a/2" {
+		t.Errorf("готово %d, в очереди %v", len(checkpoint.Results), checkpoint.Pending)
+	}
+	// Уже посещенный http://a/2 не попадает в следующий уровень повторно
+	if strings.Join(checkpoint.Next, " ") != "http://a/3" {
+		t.Errorf("следующий уровень %v, want [http://a/3]", checkpoint.Next)
+	}
+	// Прерванная попытка не считается
+	if checkpoint.Attempts["http://a/2"] != 2 {
+		t.Errorf("попыток http://a/2: %d, want 2", checkpoint.Attempts["http://a/2"])
+	}
+	if _, changed := state.snapshot(); changed {
+		t.Error("повторный snapshot без изменений отмечен как измененный")
+	}
+
+	// Из контрольной точки восстанавливается то же состояние
+	restored := newCrawlState(nil, CrawlOptions{MaxDepth: 1}, checkpoint)
+	if pending := restored.pending(); strings.Join(pending, " ") != "http://a/2" {
+		t.Errorf("после восстановления в очереди %v", pending)
+	}
+	if restored.priorAttempts("http://a/2") != 2 {
+		t.Errorf("после восстановления попыток %d, want 2", restored.priorAttempts("http://a/2"))
+	}
+}
+
+// Редирект на уже посещенную страницу не дает ее второго результата
+func TestCrawlRedirectDuplicates(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.Workers = 2
+	options.FullBody = true
+	defer func(saved func(ParseResult)) { onResult = saved }(onResult)
+	var streamed []string
+	onResult = func(result ParseResult) { streamed = append(streamed, result.URL) }
+
+	// На каждой странице есть ссылка /moved/N - редирект на саму эту страницу
+	opts := defaultFixtureOptions
+	opts.Latency, opts.Jitter, opts.BrokenRate = 0, 0, 1
+	server := httptest.NewServer(NewFixtureHandler(opts))
+	defer server.Close()
+
+	seeds := []string{server.URL + "/page/1"}
+	results := crawl(context.Background(), seeds, CrawlOptions{
+		MaxDepth: 1, AllowedDomains: defaultCrawlDomains(seeds),
+	})
+
+	finals := make(map[string]string)
+	for _, result := range results {
+		if result.URL == server.URL+"/moved/1" {
+			t.Errorf("%s -> %s выведена повторно", result.URL, result.FinalURL)
+		}
+		if result.Status != StatusOK {
+			continue
+		}
+		if first, ok := finals[result.FinalURL]; ok {
+			t.Errorf("%s и %s - одна и та же страница %s", first, result.URL, result.FinalURL)
+		}
+		finals[result.FinalURL] = result.URL
+	}
+	if len(streamed) != len(results) {
+		t.Errorf("в поток выведено %d результатов, в итогах %d", len(streamed), len(results))
+	}
+}
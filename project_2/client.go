@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	neturl "net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Настройки HTTP клиента: дополнительные заголовки, авторизация, cookies и прокси
+type ClientOptions struct {
+	Headers     http.Header         // Заголовки для всех запросов (-header)
+	Auth        map[string]hostAuth // Авторизация по хостам (-auth)
+	CookiesFile string              // Файл cookies в формате Netscape (-cookies)
+	Proxy       string              // Прокси: http://, https:// или socks5:// (пусто - из окружения)
+}
+
+// Авторизация для одного хоста: Basic (логин и пароль) или Bearer (токен)
+type hostAuth struct {
+	user     string
+	password string
+	token    string
+}
+
+// Значение флага, который можно указать несколько раз: -header A -header B
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// Функция для разбора заголовков вида "X-Token: abc"
+func parseHeaders(items []string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, item := range items {
+		name, value, ok := strings.Cut(item, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("ожидается \"Имя: значение\", получено %q", item)
+		}
+		// По User-Agent выбираются и правила robots.txt, поэтому он задается только одним флагом.
+		// Заголовки -header уходят в каждый запрос, в том числе на чужие хосты (редиректы,
+		// robots.txt, -check-links), поэтому секреты ими передавать нельзя: у -auth и -cookies
+		// они привязаны к своему хосту.
+		switch http.CanonicalHeaderKey(name) {
+		case "User-Agent":
+			return nil, fmt.Errorf("User-Agent задается флагом -user-agent")
+		case "Authorization":
+			return nil, fmt.Errorf("Authorization задается флагом -auth (уходит только своему хосту)")
+		case "Cookie":
+			return nil, fmt.Errorf("Cookie задаются файлом -cookies (уходят только своему домену)")
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// Функция для чтения секрета: "env:NAME" - из переменной окружения, иначе как есть.
+// Так пароли и токены не попадают в историю команд и список процессов.
+func readSecret(value string) (string, error) {
+	name, ok := strings.CutPrefix(value, "env:")
+	if !ok {
+		return value, nil
+	}
+	secret := os.Getenv(name)
+	if secret == "" {
+		return "", fmt.Errorf("переменная окружения %s не задана", name)
+	}
+	return secret, nil
+}
+
+// Функция для разбора авторизации по хостам:
+// "host=user:password" (Basic) или "host=bearer:TOKEN"; секрет может быть "env:NAME"
+func parseAuth(items []string) (map[string]hostAuth, error) {
+	auth := make(map[string]hostAuth)
+	for _, item := range items {
+		host, credentials, ok := strings.Cut(item, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		if !ok || host == "" {
+			return nil, fmt.Errorf("ожидается хост=логин:пароль или хост=bearer:токен, получено %q", item)
+		}
+
+		var entry hostAuth
+		if token, ok := strings.CutPrefix(credentials, "bearer:"); ok {
+			secret, err := readSecret(token)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", host, err)
+			}
+			entry.token = secret
+		} else {
+			user, password, ok := strings.Cut(credentials, ":")
+			if !ok || user == "" {
+				return nil, fmt.Errorf("%s: ожидается логин:пароль", host)
+			}
+			secret, err := readSecret(password)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", host, err)
+			}
+			entry.user, entry.password = user, secret
+		}
+		auth[host] = entry
+	}
+	return auth, nil
+}
+
+// Транспорт, который добавляет к каждому запросу User-Agent, заголовки -header и
+// авторизацию хоста. Авторизация подставляется по хосту каждого запроса,
+// поэтому при редиректе на другой хост пароль туда не уходит.
+type requestTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+	auth    map[string]hostAuth
+}
+
+func (t *requestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrip не должен менять исходный запрос
+	req = req.Clone(req.Context())
+
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", options.UserAgent)
+	}
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+
+	host := strings.ToLower(req.URL.Hostname())
+	entry, ok := t.auth[strings.ToLower(req.URL.Host)]
+	if !ok {
+		entry, ok = t.auth[host]
+	}
+	if ok && req.Header.Get("Authorization") == "" {
+		if entry.token != "" {
+			req.Header.Set("Authorization", "Bearer "+entry.token)
+		} else {
+			req.SetBasicAuth(entry.user, entry.password)
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// Закрываем простаивающие соединения базового транспорта (http.Client.CloseIdleConnections)
+func (t *requestTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// Функция для выбора прокси: из флага -proxy, а если он пуст - из HTTP_PROXY,
+// HTTPS_PROXY и NO_PROXY. Поддерживаются http://, https:// и socks5://.
+func proxyFunc(proxy string) (func(*http.Request) (*neturl.URL, error), error) {
+	if proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	parsed, err := neturl.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("неподдерживаемая схема прокси %q (http, https, socks5)", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("в адресе прокси %q нет хоста", proxy)
+	}
+	return http.ProxyURL(parsed), nil
+}
+
+// Функция для настройки общего HTTP клиента: транспорт с прокси и заголовками, cookies
+func configureClient(client *http.Client, opts ClientOptions) error {
+	proxy, err := proxyFunc(opts.Proxy)
+	if err != nil {
+		return fmt.Errorf("-proxy: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	client.Transport = &requestTransport{base: transport, headers: opts.Headers, auth: opts.Auth}
+
+	// Cookies, выставленные сайтом, отправляются в следующих запросах к нему
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	if opts.CookiesFile != "" {
+		n, err := loadNetscapeCookies(jar, opts.CookiesFile)
+		if err != nil {
+			return fmt.Errorf("-cookies: %v", err)
+		}
+		logf("🍪 Загружено cookies: %d из %s\n", n, opts.CookiesFile)
+	}
+	client.Jar = jar
+
+	return nil
+}
+
+// Функция для загрузки cookies из файла в формате Netscape (его выгружают браузеры и curl):
+// домен, поддомены (TRUE/FALSE), путь, только https (TRUE/FALSE), срок (unix), имя, значение.
+// Возвращает количество загруженных cookies; просроченные пропускаются.
+func loadNetscapeCookies(jar http.CookieJar, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	loaded := 0
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		// Строки "#HttpOnly_домен..." - это cookies с флагом HttpOnly, а не комментарии
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(line, "#HttpOnly_"); ok {
+			line, httpOnly = rest, true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return loaded, fmt.Errorf("строка %d: ожидается 7 полей через табуляцию, получено %d", lineNumber, len(fields))
+		}
+		domain, subdomains, cookiePath, secure, expires, name, value :=
+			fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+
+		cookie := &http.Cookie{
+			Name:     name,
+			Value:    value,
+			Path:     cookiePath,
+			Secure:   strings.EqualFold(secure, "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if seconds, err := strconv.ParseInt(expires, 10, 64); err == nil && seconds > 0 {
+			cookie.Expires = time.Unix(seconds, 0)
+			if cookie.Expires.Before(time.Now()) {
+				continue
+			}
+		}
+
+		// Cookie для домена и поддоменов jar распознает по атрибуту Domain
+		host := strings.TrimPrefix(domain, ".")
+		if strings.EqualFold(subdomains, "TRUE") || strings.HasPrefix(domain, ".") {
+			cookie.Domain = host
+		}
+
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&neturl.URL{Scheme: scheme, Host: host, Path: cookiePath}, []*http.Cookie{cookie})
+		loaded++
+	}
+	return loaded, scanner.Err()
+}
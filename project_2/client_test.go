@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadNetscapeCookies(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour).Unix()
+	past := time.Now().Add(-24 * time.Hour).Unix()
+
+	lines := []string{
+		"# Netscape HTTP Cookie File",
+		"",
+		fmt.Sprintf("example.com\tFALSE\t/\tFALSE\t%d\tsession\tabc", future),
+		fmt.Sprintf(".example.com\tTRUE\t/\tFALSE\t%d\tshared\tall", future),
+		"example.com\tFALSE\t/admin\tFALSE\t0\tadmin\tyes",
+		fmt.Sprintf("example.com\tFALSE\t/\tTRUE\t%d\tsecure\ts", future),
+		fmt.Sprintf("#HttpOnly_example.com\tFALSE\t/\tFALSE\t%d\thttponly\th", future),
+		fmt.Sprintf("example.com\tFALSE\t/\tFALSE\t%d\texpired\told", past),
+		fmt.Sprintf("other.org\tFALSE\t/\tFALSE\t%d\tother\to\r", future),
+	}
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	loaded, err := loadNetscapeCookies(jar, path)
+	if err != nil {
+		t.Fatalf("loadNetscapeCookies: %v", err)
+	}
+	if loaded != 6 {
+		t.Errorf("загружено %d cookies, want 6 (просроченная пропускается)", loaded)
+	}
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"http://example.com/", []string{"httponly=h", "session=abc", "shared=all"}},
+		{"https://example.com/", []string{"httponly=h", "secure=s", "session=abc", "shared=all"}},
+		{"http://example.com/admin/users", []string{"admin=yes", "httponly=h", "session=abc", "shared=all"}},
+		{"http://www.example.com/", []string{"shared=all"}}, // Только cookie для поддоменов
+		{"http://other.org/", []string{"other=o"}},          // \r в конце строки отброшен
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, _ := url.Parse(tt.url)
+			var got []string
+			for _, cookie := range jar.Cookies(u) {
+				got = append(got, cookie.Name+"="+cookie.Value)
+			}
+			sort.Strings(got)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("cookies для %s: %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadNetscapeCookiesBadLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte("# comment\nexample.com\tFALSE\t/\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	_, err := loadNetscapeCookies(jar, path)
+	if err == nil || !strings.Contains(err.Error(), "строка 2") {
+		t.Errorf("ожидалась ошибка с номером строки 2, получено: %v", err)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := parseHeaders([]string{"X-Token: abc", "Accept-Language:ru", "X-Token: def"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := headers.Values("X-Token"); strings.Join(got, ",") != "abc,def" {
+		t.Errorf("X-Token = %v", got)
+	}
+	if got := headers.Get("Accept-Language"); got != "ru" {
+		t.Errorf("Accept-Language = %q", got)
+	}
+
+	for _, bad := range []string{"без двоеточия", ": пустое имя", "user-agent: bot", "authorization: Bearer x", "Cookie: session=abc"} {
+		if _, err := parseHeaders([]string{bad}); err == nil {
+			t.Errorf("parseHeaders(%q): ожидалась ошибка", bad)
+		}
+	}
+}
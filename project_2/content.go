@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Типы содержимого, которые парсер различает
+const (
+	ContentHTML  = "html"  // Страница: заголовок, метаданные, ссылки
+	ContentJSON  = "json"  // Ответ API в JSON
+	ContentXML   = "xml"   // XML документ (RSS, sitemap, ответ API)
+	ContentText  = "text"  // Обычный текст
+	ContentOther = "other" // Картинки, PDF и прочее - не разбираем
+)
+
+// Функция для определения типа содержимого: по Content-Type, а если он не указан
+// или ничего не говорит (application/octet-stream) - по началу тела
+func detectContent(contentType string, br *bufio.Reader) string {
+	media, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		switch {
+		case media == "text/html" || media == "application/xhtml+xml":
+			return ContentHTML
+		case media == "application/json" || media == "text/json" || strings.HasSuffix(media, "+json"):
+			return ContentJSON
+		case media == "application/xml" || media == "text/xml" || strings.HasSuffix(media, "+xml"):
+			return ContentXML
+		case strings.HasPrefix(media, "text/"):
+			return ContentText
+		case media != "application/octet-stream":
+			return ContentOther
+		}
+	}
+
+	head, _ := br.Peek(charsetSniffSize)
+	return sniffContent(head)
+}
+
+// Функция для угадывания типа содержимого по первым байтам
+func sniffContent(head []byte) string {
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	switch {
+	case len(trimmed) == 0:
+		return ContentText
+	case trimmed[0] == '{' || trimmed[0] == '[':
+		return ContentJSON
+	case bytes.HasPrefix(trimmed, []byte("<?xml")):
+		if bytes.Contains(bytes.ToLower(trimmed), []byte("<html")) {
+			return ContentHTML
+		}
+		return ContentXML
+	case trimmed[0] == '<':
+		return ContentHTML
+	}
+
+	if strings.HasPrefix(http.DetectContentType(head), "text/") {
+		return ContentText
+	}
+	return ContentOther
+}
+
+// Функция для поиска кодировки в объявлении <?xml version="1.0" encoding="..."?>
+var xmlEncodingPattern = regexp.MustCompile(`^<\?xml[^>]*\sencoding\s*=\s*["']([A-Za-z0-9._-]+)["']`)
+
+func xmlDeclCharset(head []byte) (string, bool) {
+	match := xmlEncodingPattern.FindSubmatch(bytes.TrimLeft(head, " \t\r\n"))
+	if match == nil {
+		return "", false
+	}
+	return normalizeCharset(string(match[1]))
+}
+
+// Сколько символов первой строки текста показывать вместо заголовка
+const textTitleLength = 100
+
+// Функция для получения заголовка документа, который не является HTML.
+// JSON: первое поле title или name (ищем сначала на верхнем уровне, потом глубже),
+// иначе - описание структуры. XML: первый элемент <title>, иначе - имя корневого элемента.
+// Текст: первая непустая строка.
+func documentTitle(kind, text string) string {
+	switch kind {
+	case ContentJSON:
+		return jsonTitle(text)
+	case ContentXML:
+		return xmlTitle(text)
+	case ContentText:
+		for _, line := range strings.Split(text, "\n") {
+			if line = collapseSpaces(line); line != "" {
+				return truncate(line, textTitleLength)
+			}
+		}
+	}
+	return ""
+}
+
+// Заголовок JSON документа
+func jsonTitle(text string) string {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var root interface{}
+	if err := dec.Decode(&root); err != nil {
+		return "Некорректный JSON"
+	}
+
+	// Обход в ширину: поле title на верхнем уровне важнее, чем во вложенных объектах
+	queue := []interface{}{root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		switch node := node.(type) {
+		case map[string]interface{}:
+			for _, key := range []string{"title", "name"} {
+				if value, ok := node[key].(string); ok && strings.TrimSpace(value) != "" {
+					return collapseSpaces(value)
+				}
+			}
+			keys := make([]string, 0, len(node))
+			for key := range node {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				queue = append(queue, node[key])
+			}
+		case []interface{}:
+			queue = append(queue, node...)
+		}
+	}
+
+	switch root := root.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(root))
+		for key := range root {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return truncate(fmt.Sprintf("JSON-объект: %s", strings.Join(keys, ", ")), textTitleLength)
+	case []interface{}:
+		return fmt.Sprintf("JSON-массив из %d элементов", len(root))
+	default:
+		return truncate("JSON: "+jsonValueString(root), textTitleLength)
+	}
+}
+
+// Заголовок XML документа
+func xmlTitle(text string) string {
+	dec := newXMLDecoder(strings.NewReader(text))
+
+	root := ""
+	inTitle := false
+	var title strings.Builder
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if root == "" {
+				root = token.Name.Local
+			}
+			inTitle = token.Name.Local == "title"
+		case xml.CharData:
+			if inTitle {
+				title.Write(token)
+			}
+		case xml.EndElement:
+			if inTitle {
+				if value := collapseSpaces(title.String()); value != "" {
+					return value
+				}
+			}
+			inTitle = false
+		}
+	}
+
+	if root == "" {
+		return "Некорректный XML"
+	}
+	return fmt.Sprintf("XML: <%s>", root)
+}
+
+// Функция для создания нестрогого XML декодера: незакрытые теги и HTML-сущности прощаются.
+// AutoClose для HTML не включаем: в RSS <link> - обычный элемент с текстом.
+func newXMLDecoder(r io.Reader) *xml.Decoder {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	// Тело уже перекодировано в UTF-8, объявление encoding в <?xml?> можно не учитывать
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return dec
+}
+
+// Извлечение по пути элементов XML: channel/item/title, //item/title, rss/channel/@version
+type xmlPathExtractor struct {
+	path     []string // Имена элементов от корня ("*" - любой элемент)
+	anywhere bool     // Путь начинается с "//": совпадение с концом пути в любом месте документа
+	attr     string   // Взять значение атрибута ("" - текст элемента)
+	all      bool
+}
+
+// Функция для разбора пути элементов XML
+func parseXMLPath(s string) (*xmlPathExtractor, error) {
+	e := &xmlPathExtractor{}
+	rest := strings.TrimSpace(s)
+	if strings.HasPrefix(rest, "//") {
+		e.anywhere = true
+	}
+	rest = strings.Trim(rest, "/")
+
+	for _, part := range strings.Split(rest, "/") {
+		switch {
+		case part == "":
+			return nil, fmt.Errorf("пустой шаг в пути %q", s)
+		case strings.HasPrefix(part, "@"):
+			if e.attr != "" || part == "@" {
+				return nil, fmt.Errorf("атрибут может быть только последним шагом пути %q", s)
+			}
+			e.attr = part[1:]
+		case e.attr != "":
+			return nil, fmt.Errorf("атрибут может быть только последним шагом пути %q", s)
+		default:
+			e.path = append(e.path, part)
+		}
+	}
+	if len(e.path) == 0 {
+		return nil, fmt.Errorf("в пути %q нет ни одного элемента", s)
+	}
+	return e, nil
+}
+
+// Подходит ли текущий путь открытых элементов под путь извлекателя
+func (e *xmlPathExtractor) matches(stack []string) bool {
+	if len(stack) < len(e.path) || (!e.anywhere && len(stack) != len(e.path)) {
+		return false
+	}
+	tail := stack[len(stack)-len(e.path):]
+	for i, name := range e.path {
+		if name != "*" && name != tail[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *xmlPathExtractor) Extract(doc *Document) ([]string, error) {
+	dec := newXMLDecoder(strings.NewReader(doc.Text))
+
+	var (
+		values   []string
+		stack    []string
+		captures []*selectorCapture // Текст совпавших элементов (depth - глубина элемента)
+	)
+	for e.all || len(values) == 0 {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Ошибка в конце документа не отменяет того, что уже найдено
+			if len(values) > 0 {
+				break
+			}
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, token.Name.Local)
+			if !e.matches(stack) {
+				continue
+			}
+			if e.attr == "" {
+				captures = append(captures, &selectorCapture{depth: len(stack)})
+				continue
+			}
+			for _, attr := range token.Attr {
+				if attr.Name.Local == e.attr {
+					values = append(values, strings.TrimSpace(attr.Value))
+				}
+			}
+		case xml.CharData:
+			for _, c := range captures {
+				c.text.Write(token)
+			}
+		case xml.EndElement:
+			if len(captures) > 0 && captures[len(captures)-1].depth == len(stack) {
+				values = append(values, collapseSpaces(captures[len(captures)-1].text.String()))
+				captures = captures[:len(captures)-1]
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if !e.all && len(values) > 1 {
+		values = values[:1]
+	}
+	return values, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Настройки обхода сайта
+type CrawlOptions struct {
+	MaxDepth       int      // Максимальная глубина ссылок от стартовых URL
+	MaxPages       int      // Бюджет: сколько страниц можно обработать всего
+	AllowedDomains []string // Домены, которые можно обходить (вместе с поддоменами)
+	AllowedPrefix  []string // Префиксы URL, которые можно обходить
+
+	Checkpoint      string           // Файл контрольной точки ("" - не сохранять)
+	CheckpointEvery time.Duration    // Как часто сохранять контрольную точку
+	Resume          *crawlCheckpoint // Контрольная точка прошлого запуска (nil - обход с начала)
+}
+
+// Множество посещенных URL, безопасное для одновременного использования из горутин.
+// Заодно следит за бюджетом страниц.
+type VisitedSet struct {
+	mutex sync.Mutex
+	seen  map[string]bool
+	limit int
+}
+
+// Создаем множество с ограничением на количество URL (0 - без ограничения)
+func NewVisitedSet(limit int) *VisitedSet {
+	return &VisitedSet{
+		seen:  make(map[string]bool),
+		limit: limit,
+	}
+}
+
+// Добавляем URL, если его еще не было и бюджет не исчерпан
+func (v *VisitedSet) TryAdd(u string) bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if v.seen[u] {
+		return false
+	}
+	if v.limit > 0 && len(v.seen) >= v.limit {
+		return false
+	}
+	v.seen[u] = true
+	return true
+}
+
+// Отмечаем URL, куда привел редирект уже загруженной страницы (без учета бюджета:
+// страница уже скачана). false - этот URL уже был в обходе.
+func (v *VisitedSet) Mark(u string) bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if v.seen[u] {
+		return false
+	}
+	v.seen[u] = true
+	return true
+}
+
+// Восстанавливаем URL из контрольной точки (без учета бюджета)
+func (v *VisitedSet) restore(u string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.seen[u] = true
+}
+
+// Все добавленные URL в произвольном порядке
+// (сортирует вызывающий, если нужно, - уже без мьютекса)
+func (v *VisitedSet) Items() []string {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	items := make([]string, 0, len(v.seen))
+	for u := range v.seen {
+		items = append(items, u)
+	}
+	return items
+}
+
+// Сколько URL добавлено
+func (v *VisitedSet) Len() int {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return len(v.seen)
+}
+
+// Функция для нормализации URL, чтобы одна страница не обходилась дважды:
+// схема и хост в нижнем регистре, без порта по умолчанию, без фрагмента,
+// пустой путь -> "/", параметры запроса отсортированы
+func normalizeURL(raw string) (string, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	host := strings.ToLower(parsed.Hostname())
+	port := parsed.Port()
+	if (parsed.Scheme == "http" && port == "80") || (parsed.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	parsed.Host = host
+
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	if parsed.Path == "" {
+		parsed.Path = "/"
+	}
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = parsed.Query().Encode()
+	}
+
+	return parsed.String(), nil
+}
+
+// Разрешен ли URL настройками обхода
+func (o CrawlOptions) inScope(raw string) bool {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	for _, domain := range o.AllowedDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	for _, prefix := range o.AllowedPrefix {
+		if strings.HasPrefix(raw, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Функция для обхода сайта: от стартовых URL по ссылкам до MaxDepth уровней.
+// Каждый уровень обрабатывается пулом воркеров, а новые ссылки воркеры сразу
+// проверяют через общее множество посещенных URL. Туда же попадает и URL после
+// редиректов: страница, уже полученная по другому адресу, второй раз не выводится.
+// С -checkpoint состояние обхода периодически сохраняется, и прерванный обход
+// продолжается с того же места без повторной загрузки готовых страниц.
+func crawl(ctx context.Context, seeds []string, crawlOpts CrawlOptions) []ParseResult {
+	logf("🕸️  Обход сайта: глубина %d, бюджет %d страниц, воркеров: %d\n",
+		crawlOpts.MaxDepth, crawlOpts.MaxPages, options.Workers)
+	start := time.Now()
+
+	state := newCrawlState(seeds, crawlOpts, crawlOpts.Resume)
+	if resume := crawlOpts.Resume; resume != nil {
+		logf("♻️  Продолжаем обход из %s (сохранен %s): готово %d, уровень %d\n",
+			crawlOpts.Checkpoint, resume.SavedAt.Format("2006-01-02 15:04:05"), len(resume.Results), resume.Depth)
+
+		// Потоковый вывод должен содержать весь обход, а не только новые страницы
+		if onResult != nil {
+			for _, result := range state.completed() {
+				onResult(result)
+			}
+		}
+	}
+	checkpoints := startCheckpoints(crawlOpts.Checkpoint, crawlOpts.CheckpointEvery, state)
+
+	// Сколько страниц будет, заранее неизвестно: прогресс растет по уровням
+	expected := crawlOpts.MaxPages
+	if expected == 0 {
+		expected = progressMinURLs
+	}
+	p := startProgress("Обход", 0, expected)
+
+	results := state.previousResults()
+	for ctx.Err() == nil {
+		depth, level := state.currentLevel()
+		if len(level) == 0 {
+			break
+		}
+		pending := state.pending()
+		if !p.Active() {
+			logf("   Уровень %d: %d URL (осталось обработать: %d)\n", depth, len(level), len(pending))
+		}
+		p.AddTotal(len(pending))
+
+		sched := NewHostScheduler(pending, options.PerHost, hostDelay)
+		poolResults, _ := runWorkerPool(ctx, pending, options.Workers, sched, func(ctx context.Context, url string) ParseResult {
+			result := parseURL(withPriorAttempts(ctx, state.priorAttempts(url)), url)
+			result.Depth = depth
+
+			// Ссылки со страницы становятся URL следующего уровня
+			var links []string
+			if depth < crawlOpts.MaxDepth {
+				for _, link := range result.Links {
+					normalized, err := normalizeURL(link)
+					if err == nil && crawlOpts.inScope(normalized) {
+						links = append(links, normalized)
+					}
+				}
+			}
+			return state.complete(url, result, links)
+		})
+
+		levelResults, finished := state.finishLevel(poolResults)
+		results = append(results, levelResults...)
+		if !finished {
+			break
+		}
+	}
+	p.Stop()
+	checkpoints.Stop(ctx.Err() == nil)
+
+	logf("✅ Обход завершен за: %v, страниц: %d\n\n", time.Since(start), len(results))
+	return results
+}
+
+// Функция для заполнения разрешенных доменов по умолчанию: домены стартовых URL
+func defaultCrawlDomains(seeds []string) []string {
+	seen := make(map[string]bool)
+	var domains []string
+	for _, seed := range seeds {
+		if host := hostKey(seed); host != "" && !seen[host] {
+			seen[host] = true
+			domains = append(domains, host)
+		}
+	}
+	return domains
+}
+
+// Функция для разбора списка через запятую
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
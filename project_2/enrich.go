@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Частые короткие слова языков: по ним угадываем язык текста
+var languageStopwords = map[string][]string{
+	"ru": {"и", "а", "в", "не", "на", "что", "с", "по", "это", "как", "для", "из", "к", "же", "от"},
+	"uk": {"і", "та", "не", "на", "що", "з", "це", "як", "для", "від", "які", "його"},
+	"en": {"the", "and", "of", "to", "in", "is", "for", "that", "with", "on", "are", "this"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "den", "ein", "zu", "sich", "auf"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "pour", "dans", "que", "pas", "sur"},
+	"es": {"el", "la", "los", "y", "que", "del", "las", "por", "una", "para", "con", "es"},
+}
+
+// Сколько совпадений с частыми словами нужно, чтобы доверять угаданному языку
+const minLanguageHits = 3
+
+// Результат анализа текста страницы
+type textStats struct {
+	Words    int    // Количество слов в видимом тексте
+	Language string // Угаданный язык ("" - не удалось угадать)
+}
+
+// Функция для анализа видимого текста HTML: считаем слова и угадываем язык.
+// Текст внутри <script>, <style> и других raw-text элементов (кроме <title>) не учитывается.
+func analyzeText(r io.Reader) (textStats, error) {
+	index := make(map[string][]string)
+	for lang, words := range languageStopwords {
+		for _, word := range words {
+			index[word] = append(index[word], lang)
+		}
+	}
+
+	var stats textStats
+	hits := make(map[string]int)
+
+	tokenizer := newHTMLTokenizer(r)
+	skip := false // Внутри <script>, <style> и т.п.
+	for {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		switch token.Type {
+		case startTagToken:
+			skip = rawTextElements[token.Name] && !rcdataElements[token.Name] && !token.SelfClosing
+		case endTagToken:
+			skip = false
+		case textToken:
+			if skip {
+				continue
+			}
+			words := strings.FieldsFunc(token.Data, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’'
+			})
+			stats.Words += len(words)
+			for _, word := range words {
+				for _, lang := range index[strings.ToLower(word)] {
+					hits[lang]++
+				}
+			}
+		}
+	}
+
+	// Выбираем язык с наибольшим числом совпадений (при равенстве - по алфавиту)
+	best := 0
+	for lang, n := range hits {
+		if n > best || (n == best && lang < stats.Language) {
+			best = n
+			stats.Language = lang
+		}
+	}
+	if best < minLanguageHits {
+		stats.Language = ""
+	}
+
+	return stats, nil
+}
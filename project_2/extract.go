@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Загруженный документ, из которого извлекаются поля
+type Document struct {
+	URL         string
+	ContentType string // Content-Type ответа
+	Text        string // Тело в UTF-8
+
+	parsedJSON interface{} // Разобранный JSON (заполняется при первом обращении)
+	jsonErr    error
+	jsonDone   bool
+}
+
+// Функция для получения тела документа как JSON (разбирается один раз на все правила)
+func (d *Document) JSON() (interface{}, error) {
+	if !d.jsonDone {
+		dec := json.NewDecoder(strings.NewReader(d.Text))
+		dec.UseNumber()
+		d.jsonErr = dec.Decode(&d.parsedJSON)
+		d.jsonDone = true
+	}
+	return d.parsedJSON, d.jsonErr
+}
+
+// Извлекатель значений одного поля из документа.
+// Возвращает найденные значения (пустой список - ничего не найдено).
+type Extractor interface {
+	Extract(doc *Document) ([]string, error)
+}
+
+// Поле с именем и извлекателем
+type namedExtractor struct {
+	Name      string
+	Extractor Extractor
+}
+
+// Извлечение по селектору в стиле CSS: текст элементов или значение атрибута
+type selectorExtractor struct {
+	selector cssSelector
+	attr     string
+	all      bool
+}
+
+func (e *selectorExtractor) Extract(doc *Document) ([]string, error) {
+	limit := 1
+	if e.all {
+		limit = 0
+	}
+	return selectValues(strings.NewReader(doc.Text), e.selector, e.attr, limit)
+}
+
+// Извлечение регулярным выражением: первая группа, если она есть, иначе все совпадение
+type regexExtractor struct {
+	re  *regexp.Regexp
+	all bool
+}
+
+func (e *regexExtractor) Extract(doc *Document) ([]string, error) {
+	n := 1
+	if e.all {
+		n = -1
+	}
+
+	var values []string
+	for _, match := range e.re.FindAllStringSubmatch(doc.Text, n) {
+		value := match[0]
+		if len(match) > 1 {
+			value = match[1]
+		}
+		values = append(values, strings.TrimSpace(value))
+	}
+	return values, nil
+}
+
+// Шаг пути в JSON: ключ объекта, индекс массива или [*] - все элементы
+type jsonStep struct {
+	key   string
+	index int
+	kind  byte // 'k' - ключ, 'i' - индекс, '*' - все элементы
+}
+
+// Извлечение по пути в JSON, например slideshow.slides[0].title или items[*].name
+type jsonPathExtractor struct {
+	path []jsonStep
+	all  bool
+}
+
+// Функция для разбора пути в JSON. Допускается "$." в начале.
+func parseJSONPath(s string) ([]jsonStep, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "$"), ".")
+	if rest == "" {
+		return nil, nil
+	}
+
+	var path []jsonStep
+	for _, part := range strings.Split(rest, ".") {
+		key, indexes, _ := strings.Cut(part, "[")
+		if key != "" {
+			path = append(path, jsonStep{key: key, kind: 'k'})
+		}
+		if indexes == "" {
+			if key == "" {
+				return nil, fmt.Errorf("пустой шаг в пути %q", s)
+			}
+			continue
+		}
+
+		// Один или несколько индексов подряд: items[0][1]
+		for _, index := range strings.Split("["+indexes, "[")[1:] {
+			index, ok := strings.CutSuffix(index, "]")
+			if !ok {
+				return nil, fmt.Errorf("не закрыта '[' в пути %q", s)
+			}
+			if index == "*" {
+				path = append(path, jsonStep{kind: '*'})
+				continue
+			}
+			n, err := strconv.Atoi(index)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("неверный индекс %q в пути %q", index, s)
+			}
+			path = append(path, jsonStep{index: n, kind: 'i'})
+		}
+	}
+	return path, nil
+}
+
+func (e *jsonPathExtractor) Extract(doc *Document) ([]string, error) {
+	root, err := doc.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("документ не является JSON: %v", err)
+	}
+
+	nodes := []interface{}{root}
+	for _, step := range e.path {
+		var next []interface{}
+		for _, node := range nodes {
+			switch step.kind {
+			case 'k':
+				if object, ok := node.(map[string]interface{}); ok {
+					if value, ok := object[step.key]; ok {
+						next = append(next, value)
+					}
+				}
+			case 'i':
+				if array, ok := node.([]interface{}); ok && step.index < len(array) {
+					next = append(next, array[step.index])
+				}
+			case '*':
+				if array, ok := node.([]interface{}); ok {
+					next = append(next, array...)
+				}
+			}
+		}
+		nodes = next
+	}
+
+	if !e.all && len(nodes) > 1 {
+		nodes = nodes[:1]
+	}
+	values := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		values = append(values, jsonValueString(node))
+	}
+	return values, nil
+}
+
+// Функция для преобразования значения JSON в строку: строки и числа как есть,
+// объекты и массивы - компактным JSON
+func jsonValueString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// Описание поля в файле правил: ровно один из способов извлечения
+type fieldConfig struct {
+	CSS   string `json:"css"`   // Селектор, например "div.product span.price"
+	Attr  string `json:"attr"`  // Для css: взять значение атрибута вместо текста
+	Regex string `json:"regex"` // Регулярное выражение (значение - первая группа)
+	JSON  string `json:"json"`  // Путь в JSON, например "items[*].name"
+	XML   string `json:"xml"`   // Путь элементов XML, например "channel/item/title" или "//item/@id"
+	All   bool   `json:"all"`   // Собрать все совпадения, а не только первое
+}
+
+// Функция для создания извлекателя по описанию поля
+func newExtractor(config fieldConfig) (Extractor, error) {
+	kinds := 0
+	for _, expr := range []string{config.CSS, config.Regex, config.JSON, config.XML} {
+		if expr != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("нужен ровно один из способов: css, regex, json или xml")
+	}
+	if config.Attr != "" && config.CSS == "" {
+		return nil, fmt.Errorf("attr используется только вместе с css")
+	}
+
+	switch {
+	case config.CSS != "":
+		selector, err := parseSelector(config.CSS)
+		if err != nil {
+			return nil, err
+		}
+		return &selectorExtractor{selector: selector, attr: strings.ToLower(config.Attr), all: config.All}, nil
+	case config.Regex != "":
+		re, err := regexp.Compile(config.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("регулярное выражение %q: %v", config.Regex, err)
+		}
+		return &regexExtractor{re: re, all: config.All}, nil
+	case config.XML != "":
+		extractor, err := parseXMLPath(config.XML)
+		if err != nil {
+			return nil, err
+		}
+		extractor.all = config.All
+		return extractor, nil
+	default:
+		path, err := parseJSONPath(config.JSON)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathExtractor{path: path, all: config.All}, nil
+	}
+}
+
+// Правило: какие поля извлекать со страниц, URL которых подходит под шаблон
+type ExtractRule struct {
+	Pattern string // Шаблон URL: "*" - любые символы; "" - все страницы
+	match   *regexp.Regexp
+	Fields  []namedExtractor
+}
+
+// Набор правил извлечения из файла -rules
+type RuleSet struct {
+	Rules []ExtractRule
+}
+
+// Правила извлечения (nil - поля не извлекаются)
+var extractRules *RuleSet
+
+// Функция для перевода шаблона URL в регулярное выражение: * - любые символы
+func compileURLPattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		pattern = "*"
+	}
+	quoted := regexp.QuoteMeta(pattern)
+	return regexp.MustCompile("^" + strings.ReplaceAll(quoted, `\*`, ".*") + "$")
+}
+
+// Функция для загрузки правил из JSON файла вида
+//
+//	{"rules": [{"match": "https://example.com/product/*", "fields": {"price": {"css": "span.price"}}}]}
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseRules(bytes.NewReader(data))
+}
+
+// Функция для разбора правил из JSON
+func parseRules(r io.Reader) (*RuleSet, error) {
+	var file struct {
+		Rules []struct {
+			Match  string                 `json:"match"`
+			Fields map[string]fieldConfig `json:"fields"`
+		} `json:"rules"`
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("неверный формат правил: %v", err)
+	}
+
+	rules := &RuleSet{}
+	for i, raw := range file.Rules {
+		rule := ExtractRule{Pattern: raw.Match, match: compileURLPattern(raw.Match)}
+
+		// Поля по алфавиту, чтобы вывод не зависел от порядка обхода словаря
+		names := make([]string, 0, len(raw.Fields))
+		for name := range raw.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			extractor, err := newExtractor(raw.Fields[name])
+			if err != nil {
+				return nil, fmt.Errorf("правило %d (%s), поле %q: %v", i+1, valueOr(raw.Match, "*"), name, err)
+			}
+			rule.Fields = append(rule.Fields, namedExtractor{Name: name, Extractor: extractor})
+		}
+		if len(rule.Fields) == 0 {
+			return nil, fmt.Errorf("правило %d (%s): не задано ни одного поля", i+1, valueOr(raw.Match, "*"))
+		}
+		rules.Rules = append(rules.Rules, rule)
+	}
+	return rules, nil
+}
+
+// Поля, которые нужно извлечь со страницы url: из всех подходящих правил.
+// Если поле с тем же именем есть в нескольких правилах, действует первое.
+func (rs *RuleSet) For(url string) []namedExtractor {
+	if rs == nil {
+		return nil
+	}
+
+	var fields []namedExtractor
+	seen := make(map[string]bool)
+	for _, rule := range rs.Rules {
+		if !rule.match.MatchString(url) {
+			continue
+		}
+		for _, field := range rule.Fields {
+			if !seen[field.Name] {
+				seen[field.Name] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// Функция для извлечения полей из тела страницы (raw - байты как есть, charset - их кодировка).
+// Поле, которое не найдено или не извлеклось, в результат не попадает.
+func extractFields(fields []namedExtractor, url, contentType string, raw []byte, charset string) map[string][]string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	text, err := io.ReadAll(newCharsetReader(bytes.NewReader(raw), charset))
+	if err != nil {
+		return nil
+	}
+	doc := &Document{URL: url, ContentType: contentType, Text: string(text)}
+
+	result := make(map[string][]string)
+	for _, field := range fields {
+		values, err := field.Extractor.Extract(doc)
+		if err != nil || len(values) == 0 {
+			continue
+		}
+		result[field.Name] = values
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// Функция для вывода значения поля одной строкой
+func fieldValue(values []string) string {
+	return strings.Join(values, " | ")
+}
+
+// Функция для получения имен полей по алфавиту
+func fieldNames(fields map[string][]string) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONPathExtractor(t *testing.T) {
+	const doc = `{
+  "slideshow": {
+    "title": "Sample",
+    "slides": [
+      {"title": "Wake up", "items": ["a", "b"]},
+      {"title": "Overview", "items": []}
+    ]
+  },
+  "count": 12.50,
+  "ok": true,
+  "missing": null
+}`
+
+	tests := []struct {
+		path string
+		all  bool
+		want []string
+	}{
+		{path: "slideshow.title", want: []string{"Sample"}},
+		{path: "$.slideshow.title", want: []string{"Sample"}},
+		{path: "slideshow.slides[1].title", want: []string{"Overview"}},
+		{path: "slideshow.slides[*].title", all: true, want: []string{"Wake up", "Overview"}},
+		{path: "slideshow.slides[*].title", want: []string{"Wake up"}},
+		{path: "slideshow.slides[0].items", want: []string{`["a","b"]`}},
+		{path: "slideshow.slides[*].items[*]", all: true, want: []string{"a", "b"}},
+		{path: "count", want: []string{"12.50"}}, // Число как в документе
+		{path: "ok", want: []string{"true"}},
+		{path: "missing", want: []string{"null"}},
+		{path: "slideshow.slides[5].title", want: []string{}},
+		{path: "nope.title", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := parseJSONPath(tt.path)
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q): %v", tt.path, err)
+			}
+			extractor := &jsonPathExtractor{path: path, all: tt.all}
+			got, err := extractor.Extract(&Document{Text: doc})
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	for _, path := range []string{"a..b", "a[1", "a[x]", "a[-1]"} {
+		if _, err := parseJSONPath(path); err == nil {
+			t.Errorf("parseJSONPath(%q): ожидалась ошибка", path)
+		}
+	}
+}
+
+func TestJSONPathExtractorNotJSON(t *testing.T) {
+	extractor := &jsonPathExtractor{}
+	if _, err := extractor.Extract(&Document{Text: "<html>"}); err == nil {
+		t.Error("для документа не в JSON ожидалась ошибка")
+	}
+}
+
+func TestNewExtractorValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config fieldConfig
+		ok     bool
+	}{
+		{"css", fieldConfig{CSS: "h1"}, true},
+		{"css с attr", fieldConfig{CSS: "a", Attr: "href"}, true},
+		{"json", fieldConfig{JSON: "a.b"}, true},
+		{"ни одного способа", fieldConfig{}, false},
+		{"два способа", fieldConfig{CSS: "h1", Regex: "x"}, false},
+		{"attr без css", fieldConfig{JSON: "a", Attr: "href"}, false},
+		{"неверное регулярное выражение", fieldConfig{Regex: "("}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newExtractor(tt.config)
+			if (err == nil) != tt.ok {
+				t.Errorf("newExtractor: err = %v, want ok = %v", err, tt.ok)
+			}
+		})
+	}
+}
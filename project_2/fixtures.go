@@ -0,0 +1,382 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Настройки локального тестового сервера (режим -offline)
+type FixtureOptions struct {
+	Pages        int           // Сколько страниц /page/N выдается по умолчанию
+	Latency      time.Duration // Задержка перед ответом
+	Jitter       time.Duration // Случайная добавка к задержке: от 0 до Jitter
+	PageSize     int           // Примерный размер страницы в байтах
+	Encoding     string        // Кодирование ответа: identity, gzip или chunked
+	Charset      string        // Кодировка страниц: utf-8, windows-1251 или koi8-r
+	Declare      string        // Где объявлена кодировка: header, meta или none (только угадывание)
+	ErrorRate    float64       // Доля ответов с ошибкой 5xx/429 (от 0 до 1)
+	RedirectRate float64       // Доля страниц, которые отвечают цепочкой редиректов (от 0 до 1)
+	Redirects    int           // Длина цепочки редиректов
+	BrokenRate   float64       // Доля страниц со ссылками на несуществующую страницу и цикл редиректов (от 0 до 1)
+}
+
+// Настройки тестового сервера по умолчанию
+var defaultFixtureOptions = FixtureOptions{
+	Pages:     20,
+	Latency:   200 * time.Millisecond,
+	Jitter:    100 * time.Millisecond,
+	PageSize:  8 * 1024,
+	Encoding:  "identity",
+	Charset:   "utf-8",
+	Declare:   "header",
+	Redirects: 2,
+}
+
+// Поддерживаемые кодирования ответа тестового сервера
+var fixtureEncodings = []string{"identity", "gzip", "chunked"}
+
+// Поддерживаемые кодировки страниц тестового сервера и способы их объявления
+var fixtureCharsets = []string{"utf-8", "windows-1251", "koi8-r"}
+var fixtureDeclares = []string{"header", "meta", "none"}
+
+// Локальный тестовый сервер со сгенерированными страницами
+type FixtureServer struct {
+	URL      string // Адрес сервера, например http://127.0.0.1:41234
+	opts     FixtureOptions
+	listener net.Listener
+	server   *http.Server
+}
+
+// Запускаем тестовый сервер на адресе addr ("127.0.0.1:0" - свободный порт)
+func StartFixtureServer(addr string, opts FixtureOptions) (*FixtureServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &FixtureServer{
+		URL:      "http://" + listener.Addr().String(),
+		opts:     opts,
+		listener: listener,
+		server:   &http.Server{Handler: NewFixtureHandler(opts)},
+	}
+	go fs.server.Serve(listener)
+
+	return fs, nil
+}
+
+// URL всех страниц по умолчанию
+func (fs *FixtureServer) PageURLs() []string {
+	urls := make([]string, 0, fs.opts.Pages)
+	for i := 1; i <= fs.opts.Pages; i++ {
+		urls = append(urls, fmt.Sprintf("%s/page/%d", fs.URL, i))
+	}
+	return urls
+}
+
+// Останавливаем сервер
+func (fs *FixtureServer) Close() error {
+	return fs.server.Close()
+}
+
+// Создаем обработчик тестового сервера. Его можно использовать и отдельно,
+// например с httptest.NewServer.
+//
+//	/robots.txt      - разрешает все, кроме /private/
+//	/sitemap.xml     - список страниц по умолчанию
+//	/page/N          - сгенерированная страница со ссылками на N*3+1..N*3+3
+//	/private/...     - страница, запрещенная robots.txt
+//	/data/N.json     - ответ API в JSON
+//	/data/N.xml      - RSS-лента в XML
+//	/data/N.txt      - обычный текст
+//	/moved/N         - постоянный редирект на /page/N
+//	/loop/N          - цикл редиректов /loop/N -> /loop/N/back -> /loop/N
+//	/missing/N       - 404 (на такие ссылки ведут страницы с битыми ссылками)
+//	/headers         - заголовки запроса в JSON (для проверки -header, -auth и -cookies)
+//
+// Параметры запроса страницы переопределяют настройки для одного ответа:
+// latency=300ms, size=50000, status=503, hops=3, encoding=gzip, charset=koi8-r, declare=meta.
+func NewFixtureHandler(opts FixtureOptions) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+	})
+
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+		fmt.Fprintln(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+		for i := 1; i <= opts.Pages; i++ {
+			fmt.Fprintf(w, "  <url><loc>%s/page/%d</loc></url>\n", base, i)
+		}
+		fmt.Fprintln(w, `</urlset>`)
+	})
+
+	mux.HandleFunc("/page/", func(w http.ResponseWriter, r *http.Request) {
+		servePage(w, r, opts)
+	})
+	mux.HandleFunc("/private/", func(w http.ResponseWriter, r *http.Request) {
+		servePage(w, r, opts)
+	})
+	mux.HandleFunc("/data/", serveData)
+	mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+		headers := make(map[string]string)
+		for name := range r.Header {
+			headers[name] = r.Header.Get(name)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"headers": headers})
+	})
+	mux.HandleFunc("/moved/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page/"+strings.TrimPrefix(r.URL.Path, "/moved/"), http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop/", func(w http.ResponseWriter, r *http.Request) {
+		if back, ok := strings.CutSuffix(r.URL.Path, "/back"); ok {
+			http.Redirect(w, r, back, http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, r.URL.Path+"/back", http.StatusFound)
+	})
+
+	return mux
+}
+
+// Функция для ответа сгенерированной страницей (с задержкой, ошибками и редиректами)
+func servePage(w http.ResponseWriter, r *http.Request, opts FixtureOptions) {
+	query := r.URL.Query()
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/page/"), "/private/"))
+	if err != nil || n < 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Задержка имитирует медленный сервер; отмена запроса клиентом прерывает ожидание
+	latency := opts.Latency
+	if opts.Jitter > 0 {
+		latency += time.Duration(rand.Int63n(int64(opts.Jitter)))
+	}
+	if d, err := time.ParseDuration(query.Get("latency")); err == nil {
+		latency = d
+	}
+	select {
+	case <-time.After(latency):
+	case <-r.Context().Done():
+		return
+	}
+
+	// Ошибки: явно заданный статус или случайная ошибка с вероятностью ErrorRate
+	status := 0
+	if code, err := strconv.Atoi(query.Get("status")); err == nil {
+		status = code
+	} else if opts.ErrorRate > 0 && rand.Float64() < opts.ErrorRate {
+		status = []int{http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusTooManyRequests}[rand.Intn(4)]
+	}
+	if status >= 400 {
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if status != 0 && status != http.StatusOK {
+		// Остальные коды (204, 300...) - без тела, для проверки -strict-status
+		w.WriteHeader(status)
+		return
+	}
+
+	// Редиректы: цепочка из hops переходов на ту же страницу.
+	// Какие страницы редиректят, определяется номером страницы, чтобы запуски совпадали.
+	hops := -1
+	if h, err := strconv.Atoi(query.Get("hops")); err == nil {
+		hops = h
+	} else if opts.RedirectRate > 0 && fixtureFraction("page", n) < opts.RedirectRate {
+		hops = opts.Redirects
+	}
+	if hops > 0 {
+		target := *r.URL
+		q := target.Query()
+		q.Set("hops", strconv.Itoa(hops-1))
+		target.RawQuery = q.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+		return
+	}
+
+	size := opts.PageSize
+	if s, err := strconv.Atoi(query.Get("size")); err == nil {
+		size = s
+	}
+	charset := opts.Charset
+	if c, ok := normalizeCharset(query.Get("charset")); ok {
+		charset = c
+	}
+	declare := opts.Declare
+	if d := query.Get("declare"); d != "" {
+		declare = d
+	}
+
+	broken := opts.BrokenRate > 0 && fixtureFraction("links", n) < opts.BrokenRate
+	page := string(encodeCharset(fixturePage(n, size, charset, declare == "meta", broken), charset))
+
+	// Валидаторы для условных запросов: страница не меняется, пока не изменились параметры
+	etag := fixtureETag(page)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", fixtureModified)
+	if fixtureNotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	encoding := opts.Encoding
+	if e := query.Get("encoding"); e != "" {
+		encoding = e
+	}
+
+	if declare == "header" {
+		w.Header().Set("Content-Type", "text/html; charset="+charset)
+	} else {
+		w.Header().Set("Content-Type", "text/html")
+	}
+	switch encoding {
+	case "gzip":
+		// Go-клиент сам добавляет Accept-Encoding: gzip и распаковывает ответ
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			io.WriteString(w, page)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		io.WriteString(gz, page)
+		gz.Close()
+	case "chunked":
+		// Без Content-Length и с промежуточными сбросами ответ уходит частями
+		flusher, _ := w.(http.Flusher)
+		for len(page) > 0 {
+			chunk := min(len(page), 1024)
+			io.WriteString(w, page[:chunk])
+			page = page[chunk:]
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	default:
+		w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+		io.WriteString(w, page)
+	}
+}
+
+// Функция для ответа документом, который не является HTML: JSON, XML или текст
+func serveData(w http.ResponseWriter, r *http.Request) {
+	name, ext, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/data/"), ".")
+	n, err := strconv.Atoi(name)
+	if err != nil || n < 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch ext {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id": %d, "status": "ok", "item": {"title": "Запись %d", "price": %d.50, "tags": ["go", "json"]}, "links": [{"href": "/page/%d"}, {"href": "/page/%d"}]}`+"\n",
+			n, n, n*10, n*3+1, n*3+2)
+	case "xml":
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<rss version=\"2.0\">\n<channel>\n<title>Лента %d</title>\n", n)
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(w, "<item id=\"%d\"><title>Новость %d.%d</title><link>/page/%d</link></item>\n", n*3+i, n, i, n*3+i)
+		}
+		fmt.Fprintln(w, "</channel>\n</rss>")
+	case "txt":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "\nЗаметка %d\n\nВерсия: 1.%d.0\nГорутины и каналы - основа конкурентности в Go.\n", n, n)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Время изменения всех страниц тестового сервера (для Last-Modified)
+var fixtureModified = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+
+// Функция для вычисления ETag страницы по ее содержимому
+func fixtureETag(page string) string {
+	h := fnv.New64a()
+	io.WriteString(h, page)
+	return fmt.Sprintf(`"%x"`, h.Sum64())
+}
+
+// Проверяем условный запрос, как велит RFC 9110: If-Modified-Since учитывается,
+// только если в запросе нет If-None-Match (ETag точнее даты изменения)
+func fixtureNotModified(r *http.Request, etag string) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, _ := http.ParseTime(fixtureModified)
+	return !modified.After(since)
+}
+
+// Псевдослучайное, но постоянное для страницы число от 0 до 1
+// (kind разделяет независимые свойства страницы: редиректы, битые ссылки)
+func fixtureFraction(kind string, n int) float64 {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s-%d", kind, n)
+	return float64(h.Sum32()) / float64(1<<32)
+}
+
+// Функция для генерации HTML страницы номер n размером примерно size байт
+// (metaCharset - объявить кодировку в <meta charset>, broken - добавить битую ссылку,
+// цикл редиректов и ссылку через редирект)
+func fixturePage(n, size int, charset string, metaCharset, broken bool) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html lang=\"ru\">\n<head>\n")
+	if metaCharset {
+		fmt.Fprintf(&b, "<meta charset=\"%s\">\n", charset)
+	}
+	fmt.Fprintf(&b, "<title>Тестовая страница %d &mdash; &laquo;Горутины&raquo; &amp; каналы</title>\n", n)
+	fmt.Fprintf(&b, "<meta name=\"description\" content=\"Сгенерированная страница %d для демонстрации\">\n", n)
+	fmt.Fprintf(&b, "<meta property=\"og:title\" content=\"Страница %d\">\n", n)
+	fmt.Fprintf(&b, "<link rel=\"canonical\" href=\"/page/%d\">\n", n)
+	fmt.Fprintf(&b, "</head>\n<body>\n<h1>Страница %d</h1>\n<nav>\n", n)
+	for i := 1; i <= 3; i++ {
+		fmt.Fprintf(&b, "<a href=\"/page/%d\">Страница %d</a>\n", n*3+i, n*3+i)
+	}
+	fmt.Fprintf(&b, "<a href=\"/private/%d\">Закрытая страница</a>\n", n)
+	if broken {
+		fmt.Fprintf(&b, "<a href=\"/missing/%d\">Удаленная страница</a>\n", n)
+		fmt.Fprintf(&b, "<a href=\"/loop/%d\">Бесконечный редирект</a>\n", n)
+		fmt.Fprintf(&b, "<a href=\"/moved/%d\">Переехавшая страница</a>\n", n)
+	}
+	b.WriteString("</nav>\n")
+
+	// Добиваем страницу текстом до нужного размера
+	const paragraph = "<p>Горутины очень дешевые, и их можно запускать тысячами, а каналы помогают безопасно передавать данные между ними.</p>\n"
+	for b.Len()+len(paragraph)+len("</body>\n</html>\n") <= size {
+		b.WriteString(paragraph)
+	}
+
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
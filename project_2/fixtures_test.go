@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Функция для запуска тестового сервера без задержек и ошибок
+func newTestFixture(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	opts := defaultFixtureOptions
+	opts.Latency, opts.Jitter = 0, 0
+	server := httptest.NewServer(NewFixtureHandler(opts))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFixtureConditionalRequests(t *testing.T) {
+	server := newTestFixture(t)
+	pageURL := server.URL + "/page/1"
+
+	resp, err := http.Get(pageURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	modified := resp.Header.Get("Last-Modified")
+	if etag == "" || modified == "" {
+		t.Fatalf("нет валидаторов: ETag %q, Last-Modified %q", etag, modified)
+	}
+
+	tests := []struct {
+		name       string
+		noneMatch  string
+		modSince   string
+		wantStatus int
+	}{
+		{"совпал ETag", etag, "", http.StatusNotModified},
+		{"совпал слабый ETag из списка", `"other", W/` + etag, "", http.StatusNotModified},
+		{"If-None-Match: *", "*", "", http.StatusNotModified},
+		{"совпала дата", "", modified, http.StatusNotModified},
+		{"дата позже изменения", "", "Tue, 01 Jan 2030 00:00:00 GMT", http.StatusNotModified},
+		{"дата раньше изменения", "", "Sun, 01 Jan 2023 00:00:00 GMT", http.StatusOK},
+		// RFC 9110: при If-None-Match дата не проверяется
+		{"ETag не совпал, дата совпала", `"stale"`, modified, http.StatusOK},
+		{"без условий", "", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, pageURL, nil)
+			if tt.noneMatch != "" {
+				req.Header.Set("If-None-Match", tt.noneMatch)
+			}
+			if tt.modSince != "" {
+				req.Header.Set("If-Modified-Since", tt.modSince)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("статус %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestParseFixturePage(t *testing.T) {
+	server := newTestFixture(t)
+
+	tests := []struct {
+		query   string
+		charset string
+	}{
+		{"", "utf-8"},
+		{"?charset=windows-1251", "windows-1251"},
+		{"?charset=koi8-r&declare=meta", "koi8-r"},
+		{"?charset=koi8-r&declare=none", "koi8-r"},
+		{"?encoding=gzip", "utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			result := parseURL(context.Background(), server.URL+"/page/3"+tt.query)
+			if result.Status != StatusOK {
+				t.Fatalf("статус %s: %v", result.Status, result.Error)
+			}
+			if want := "Тестовая страница 3 — «Горутины» & каналы"; result.Title != want {
+				t.Errorf("Title = %q, want %q", result.Title, want)
+			}
+			if result.Charset != tt.charset {
+				t.Errorf("Charset = %q, want %q", result.Charset, tt.charset)
+			}
+		})
+	}
+}
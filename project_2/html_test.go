@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Функция для записи токенов документа в виде коротких строк: "<a href=/x>", "text:Hi", "</a>"
+func tokenize(t *testing.T, input string) []string {
+	t.Helper()
+
+	var tokens []string
+	tokenizer := newHTMLTokenizer(strings.NewReader(input))
+	for {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
+			return tokens
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+
+		switch token.Type {
+		case startTagToken:
+			var b strings.Builder
+			b.WriteString("<" + token.Name)
+			for _, attr := range token.Attrs {
+				fmt.Fprintf(&b, " %s=%s", attr.Name, attr.Value)
+			}
+			if token.SelfClosing {
+				b.WriteString("/")
+			}
+			tokens = append(tokens, b.String()+">")
+		case endTagToken:
+			tokens = append(tokens, "</"+token.Name+">")
+		case textToken:
+			tokens = append(tokens, "text:"+token.Data)
+		case commentToken:
+			tokens = append(tokens, "comment:"+token.Data)
+		case doctypeToken:
+			tokens = append(tokens, "doctype:"+token.Data)
+		}
+	}
+}
+
+func TestHTMLTokenizer(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "теги и текст",
+			input: `<p class="a">Привет</p>`,
+			want:  []string{"<p class=a>", "text:Привет", "</p>"},
+		},
+		{
+			name:  "регистр имен и атрибуты без кавычек",
+			input: `<A HREF=/x Target='_blank' hidden>`,
+			want:  []string{"<a href=/x target=_blank hidden=>"},
+		},
+		{
+			name:  "самозакрывающийся тег",
+			input: `<br/><img src="a.png" />`,
+			want:  []string{"<br/>", "<img src=a.png/>"},
+		},
+		{
+			name:  "сущности в тексте и атрибутах (&nbsp; - обычный пробел)",
+			input: `<a title="Tom &amp; Jerry">&lt;b&gt; &#1071; &#x44F; &nbsp;</a>`,
+			want:  []string{"<a title=Tom & Jerry>", "text:<b> Я я  ", "</a>"},
+		},
+		{
+			name:  "комментарий и doctype",
+			input: `<!DOCTYPE html><!-- <p>не тег</p> --><p>`,
+			want:  []string{"doctype:DOCTYPE html", "comment: <p>не тег</p> ", "<p>"},
+		},
+		{
+			name:  "скрипт не разбирается как HTML",
+			input: `<script>if (a < b) { x = "</p>" }</script><p>`,
+			want:  []string{"<script>", `text:if (a < b) { x = "</p>" }`, "</script>", "<p>"},
+		},
+		{
+			name:  "сущности в title раскодируются",
+			input: `<title>A &amp; <b>B</title>`,
+			want:  []string{"<title>", "text:A & <b>B", "</title>"},
+		},
+		{
+			name:  "одиночный '<' - это текст",
+			input: `1 < 2`,
+			want:  []string{"text:1 ", "text:<", "text: 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenize(t, tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q)\n got: %q\nwant: %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Сколько редиректов подряд проходим при проверке ссылки
+const maxLinkRedirects = 10
+
+// Переход по редиректу: код ответа и адрес, куда он ведет
+type Redirect struct {
+	StatusCode int    // 301, 302, 307...
+	URL        string // Абсолютный адрес из Location
+}
+
+// Ошибка: цепочка редиректов зациклилась или оказалась слишком длинной
+type RedirectError struct {
+	Loop bool   // Адрес повторился
+	URL  string // Адрес, на котором цепочка прервана
+}
+
+func (e *RedirectError) Error() string {
+	if e.Loop {
+		return "цикл редиректов: повторно " + e.URL
+	}
+	return fmt.Sprintf("больше %d редиректов подряд (последний - %s)", maxLinkRedirects, e.URL)
+}
+
+// Функция для форматирования цепочки редиректов: "301 https://a -> 302 https://b"
+func formatRedirects(redirects []Redirect) string {
+	parts := make([]string, 0, len(redirects))
+	for _, r := range redirects {
+		parts = append(parts, fmt.Sprintf("%d %s", r.StatusCode, r.URL))
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// Отчет о проверке ссылок: какие ссылки на каких страницах и что с ними
+type LinkReport struct {
+	Pages   []string               // Страницы со ссылками в порядке обработки
+	Links   map[string][]string    // Страница -> ссылки на ней (без дубликатов)
+	Results map[string]ParseResult // Ссылка -> результат проверки
+	Order   []string               // Ссылки в порядке первого появления
+}
+
+// Функция для сбора ссылок со страниц: только http(s), без фрагментов и дубликатов
+func collectLinks(pages []ParseResult) *LinkReport {
+	report := &LinkReport{
+		Links:   make(map[string][]string),
+		Results: make(map[string]ParseResult),
+	}
+	seen := make(map[string]bool)
+
+	for _, page := range pages {
+		if page.Status != StatusOK || len(page.Links) == 0 {
+			continue
+		}
+
+		onPage := make(map[string]bool)
+		for _, link := range page.Links {
+			if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+				continue
+			}
+			normalized, err := normalizeURL(link)
+			if err != nil || onPage[normalized] {
+				continue
+			}
+			onPage[normalized] = true
+			report.Links[page.URL] = append(report.Links[page.URL], normalized)
+
+			if !seen[normalized] {
+				seen[normalized] = true
+				report.Order = append(report.Order, normalized)
+			}
+		}
+		if len(report.Links[page.URL]) > 0 {
+			report.Pages = append(report.Pages, page.URL)
+		}
+	}
+	return report
+}
+
+// Страницы, на которых найдена ссылка
+func (r *LinkReport) Referrers(link string) []string {
+	var pages []string
+	for _, page := range r.Pages {
+		if contains(r.Links[page], link) {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// Функция для проверки ссылок со всех страниц: каждая уникальная ссылка проверяется
+// один раз пулом воркеров с учетом ограничений по хостам и robots.txt
+func checkLinks(ctx context.Context, pages []ParseResult) *LinkReport {
+	report := collectLinks(pages)
+	logf("🔗 Проверка ссылок: %d уникальных на %d страницах, воркеров: %d\n",
+		len(report.Order), len(report.Pages), options.Workers)
+	start := time.Now()
+
+	// Редиректы проходим сами, чтобы записать всю цепочку и заметить цикл
+	client := *httpClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	p := startProgress("Ссылки", len(report.Order), len(report.Order))
+	sched := NewHostScheduler(report.Order, options.PerHost, hostDelay)
+	results, _ := runWorkerPool(ctx, report.Order, options.Workers, sched, func(ctx context.Context, link string) ParseResult {
+		if ctx.Err() != nil {
+			return cancelledResult(link)
+		}
+		if robots != nil && !robots.Allowed(ctx, link) {
+			return ParseResult{URL: link, Status: StatusBlocked}
+		}
+		return withRetry(ctx, link, func() ParseResult {
+			return checkLink(ctx, &client, link)
+		})
+	})
+	p.Stop()
+
+	for _, result := range results {
+		report.Results[result.URL] = result
+	}
+
+	logf("✅ Проверка ссылок завершена за: %v\n\n", time.Since(start))
+	return report
+}
+
+// Функция для проверки одной ссылки: HEAD (или GET, если HEAD не поддерживается)
+// по всей цепочке редиректов
+func checkLink(ctx context.Context, client *http.Client, link string) ParseResult {
+	start := time.Now()
+	result := ParseResult{URL: link}
+	visited := map[string]bool{link: true}
+
+	current := link
+	for {
+		resp, err := requestLink(ctx, client, current)
+		if err != nil {
+			failed := errorResult(ctx, link, err, start)
+			failed.Redirects = result.Redirects
+			return failed
+		}
+		resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			result.FinalURL = current
+			result.StatusCode = resp.StatusCode
+			result.Headers = responseHeaders(resp.Header)
+			result.BodySize = resp.ContentLength
+			result.Status = StatusOK
+			if resp.StatusCode >= 400 {
+				result.Status = StatusError
+				result.Error = &HTTPStatusError{
+					StatusCode: resp.StatusCode,
+					RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+				}
+			}
+			result.Elapsed = time.Since(start)
+			return result
+		}
+
+		next, err := resp.Request.URL.Parse(location)
+		if err != nil {
+			failed := errorResult(ctx, link, fmt.Errorf("неверный Location %q: %v", location, err), start)
+			failed.Redirects = result.Redirects
+			return failed
+		}
+		next.Fragment = ""
+		result.Redirects = append(result.Redirects, Redirect{StatusCode: resp.StatusCode, URL: next.String()})
+
+		// Цикл или слишком длинная цепочка - ссылка битая, повтор не поможет
+		var redirectErr *RedirectError
+		if visited[next.String()] {
+			redirectErr = &RedirectError{Loop: true, URL: next.String()}
+		} else if len(result.Redirects) >= maxLinkRedirects {
+			redirectErr = &RedirectError{URL: next.String()}
+		}
+		if redirectErr != nil {
+			failed := errorResult(ctx, link, redirectErr, start)
+			failed.FinalURL = next.String()
+			failed.StatusCode = resp.StatusCode
+			failed.Redirects = result.Redirects
+			return failed
+		}
+
+		visited[next.String()] = true
+		current = next.String()
+	}
+}
+
+// Функция для одного запроса без перехода по редиректам. Сначала HEAD: он не скачивает тело.
+// Некоторые серверы не поддерживают HEAD (405, 501) или отвечают на него иначе, чем на GET,
+// поэтому при ошибке 4xx/5xx (кроме 429) ответ перепроверяется через GET.
+func requestLink(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	resp, err := doLinkRequest(ctx, client, http.MethodHead, url)
+	if err != nil || resp.StatusCode < 400 || resp.StatusCode == http.StatusTooManyRequests {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	resp, err = doLinkRequest(ctx, client, http.MethodGet, url)
+	if err != nil {
+		return nil, err
+	}
+	// Тело не нужно: дочитываем немного, чтобы соединение можно было переиспользовать
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	return resp, nil
+}
+
+// Функция для запроса ссылки методом method
+func doLinkRequest(ctx context.Context, client *http.Client, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", options.UserAgent)
+	return client.Do(req)
+}
+
+// Функция для вывода отчета о ссылках, сгруппированного по страницам.
+// Битые ссылки и редиректы показываются всегда, рабочие - только с -details.
+func printLinkReport(report *LinkReport) {
+	fmt.Println("📊 Отчет о ссылках:")
+	fmt.Println(strings.Repeat("-", 80))
+
+	for _, page := range report.Pages {
+		var lines []string
+		for _, link := range report.Links[page] {
+			result := report.Results[link]
+			if line, ok := linkLine(result); ok {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		fmt.Printf("📄 %s\n", page)
+		for _, line := range lines {
+			fmt.Println("   " + line)
+		}
+	}
+
+	// Сводка по уникальным ссылкам
+	var ok, redirected, broken, loops, skipped int
+	for _, link := range report.Order {
+		result := report.Results[link]
+		var redirectErr *RedirectError
+		switch {
+		case result.Status == StatusBlocked || result.Status == StatusCancelled:
+			skipped++
+		case result.Status == StatusError:
+			broken++
+			if errors.As(result.Error, &redirectErr) && redirectErr.Loop {
+				loops++
+			}
+		case len(result.Redirects) > 0:
+			redirected++
+		default:
+			ok++
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("🔗 Ссылок: %d на %d страницах: ✅ рабочих %d, ↪️  через редирект %d, ❌ битых %d (из них циклов %d), ⏭️  пропущено %d\n",
+		len(report.Order), len(report.Pages), ok, redirected, broken, loops, skipped)
+	fmt.Println()
+}
+
+// Строка отчета для ссылки; false - ссылку показывать не нужно
+func linkLine(result ParseResult) (string, bool) {
+	chain := ""
+	if len(result.Redirects) > 0 {
+		chain = " -> " + formatRedirects(result.Redirects)
+	}
+
+	var redirectErr *RedirectError
+	switch {
+	case result.Status == StatusBlocked:
+		return fmt.Sprintf("🚫 %s: пропущена - запрещено robots.txt", result.URL), showDetails
+	case result.Status == StatusCancelled:
+		return fmt.Sprintf("⏹️  %s: не проверена", result.URL), true
+	case errors.As(result.Error, &redirectErr):
+		icon := "🔁"
+		if !redirectErr.Loop {
+			icon = "➰"
+		}
+		return fmt.Sprintf("%s %s%s: %v", icon, result.URL, chain, result.Error), true
+	case result.Error != nil:
+		return fmt.Sprintf("❌ %s%s: %v", result.URL, chain, result.Error), true
+	case len(result.Redirects) > 0:
+		return fmt.Sprintf("↪️  %s%s: %d", result.URL, chain, result.StatusCode), true
+	default:
+		return fmt.Sprintf("✅ %s: %d", result.URL, result.StatusCode), showDetails
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// Сервер со ссылками всех видов: рабочая, битая, без HEAD, с редиректами и с циклом
+func newLinkServer(t *testing.T, heads *atomic.Int64) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+		}
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.Handle("/moved", http.RedirectHandler("/moved-again#top", http.StatusMovedPermanently))
+	mux.Handle("/moved-again", http.RedirectHandler("/ok", http.StatusFound))
+	mux.Handle("/loop-a", http.RedirectHandler("/loop-b", http.StatusFound))
+	mux.Handle("/loop-b", http.RedirectHandler("/loop-a", http.StatusFound))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckLinks(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.Workers = 3
+
+	var heads atomic.Int64
+	server := newLinkServer(t, &heads)
+	pages := []ParseResult{
+		{URL: "page-1", Status: StatusOK, PageMeta: PageMeta{Links: []string{
+			server.URL + "/ok", server.URL + "/ok#section", server.URL + "/gone", "mailto:someone@example.com",
+		}}},
+		{URL: "page-2", Status: StatusOK, PageMeta: PageMeta{Links: []string{
+			server.URL + "/ok", server.URL + "/no-head", server.URL + "/moved", server.URL + "/loop-a",
+		}}},
+		{URL: "failed", Status: StatusError, PageMeta: PageMeta{Links: []string{server.URL + "/never"}}},
+	}
+
+	report := checkLinks(context.Background(), pages)
+
+	// Ссылки без дубликатов и фрагментов, только http(s) и только с успешных страниц
+	if len(report.Order) != 5 {
+		t.Fatalf("уникальных ссылок %d, want 5: %v", len(report.Order), report.Order)
+	}
+	if got := report.Referrers(server.URL + "/ok"); len(got) != 2 {
+		t.Errorf("/ok найдена на %v, want на обеих страницах", got)
+	}
+	if heads.Load() != 2 {
+		t.Errorf("HEAD-запросов к /ok: %d, want 2 (прямая ссылка и конец цепочки редиректов)", heads.Load())
+	}
+
+	tests := []struct {
+		path       string
+		status     ResultStatus
+		statusCode int
+		redirects  int
+	}{
+		{"/ok", StatusOK, 200, 0},
+		{"/gone", StatusError, 410, 0},
+		{"/no-head", StatusOK, 200, 0}, // HEAD не поддерживается - перепроверено через GET
+		{"/moved", StatusOK, 200, 2},
+		{"/loop-a", StatusError, 302, 2},
+	}
+	for _, tt := range tests {
+		result, ok := report.Results[server.URL+tt.path]
+		if !ok {
+			t.Errorf("%s не проверена", tt.path)
+			continue
+		}
+		if result.Status != tt.status || result.StatusCode != tt.statusCode || len(result.Redirects) != tt.redirects {
+			t.Errorf("%s: статус %s, код %d, редиректов %d; want %s, %d, %d",
+				tt.path, result.Status, result.StatusCode, len(result.Redirects), tt.status, tt.statusCode, tt.redirects)
+		}
+	}
+
+	moved := report.Results[server.URL+"/moved"]
+	if moved.FinalURL != server.URL+"/ok" || moved.Redirects[0].StatusCode != 301 || moved.Redirects[0].URL != server.URL+"/moved-again" {
+		t.Errorf("цепочка /moved: %s, итог %s", formatRedirects(moved.Redirects), moved.FinalURL)
+	}
+
+	var redirectErr *RedirectError
+	if loop := report.Results[server.URL+"/loop-a"]; !errors.As(loop.Error, &redirectErr) || !redirectErr.Loop {
+		t.Errorf("/loop-a: ошибка %v, want цикл редиректов", loop.Error)
+	}
+}
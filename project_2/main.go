@@ -1,132 +1,410 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
+	neturl "net/url"
+	"os"
+	"os/signal"
+	"sort"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 )
 
+// Итог обработки URL
+type ResultStatus string
+
+const (
+	StatusOK        ResultStatus = "ok"        // Страница успешно обработана
+	StatusError     ResultStatus = "error"     // Ошибка при загрузке или разборе
+	StatusBlocked   ResultStatus = "blocked"   // Пропущена: запрещено robots.txt
+	StatusCancelled ResultStatus = "cancelled" // Не завершена: истек -deadline или нажат Ctrl-C
+)
+
 // Структура для хранения результата парсинга
 type ParseResult struct {
-	URL     string        // URL страницы
-	Title   string        // Заголовок страницы
-	Error   error         // Ошибка при парсинге
-	Elapsed time.Duration // Время выполнения
+	URL          string              // URL страницы
+	FinalURL     string              // URL после всех редиректов
+	StatusCode   int                 // HTTP статус ответа (0 - ответа не было)
+	PageMeta                         // Заголовок и другие метаданные страницы
+	Status       ResultStatus        // Итог обработки
+	Error        error               // Ошибка при парсинге
+	ErrorClass   ErrorClass          // Класс последней ошибки
+	Attempts     int                 // Сколько было попыток
+	Depth        int                 // Глубина страницы при обходе сайта (0 - стартовый URL)
+	Bytes        int64               // Сколько байт тела ответа прочитано
+	BodySize     int64               // Размер тела по Content-Length (-1 - неизвестен)
+	Truncated    string              // Почему тело прочитано не полностью: head, max-body ("" - полностью)
+	Cache        string              // Откуда страница при включенном кеше: hit, revalidated, miss
+	Words        int                 // Количество слов в тексте страницы (этап enrich конвейера)
+	TextLanguage string              // Язык, угаданный по тексту страницы (этап enrich конвейера)
+	Fields       map[string][]string // Поля, извлеченные по правилам -rules
+	Redirects    []Redirect          // Цепочка редиректов до FinalURL
+	Headers      map[string]string   // Интересные заголовки ответа (-headers)
+	Referrers    []string            // Страницы, на которых найдена ссылка (режим -check-links)
+	Elapsed      time.Duration       // Время выполнения (всех попыток вместе с паузами)
+
+	duplicate bool // Редирект привел на страницу, уже полученную при обходе по другому URL
 }
 
-// Функция для парсинга заголовка страницы
-func parsePageTitle(url string) ParseResult {
-	start := time.Now()
+// Настройки парсера (заполняются из флагов командной строки)
+type Options struct {
+	Workers      int           // Количество воркеров параллельного парсинга
+	PerHost      int           // Максимум одновременных запросов к одному хосту (0 - без ограничения)
+	HostDelay    time.Duration // Минимальная пауза между запросами к одному хосту
+	UserAgent    string        // User-Agent для запросов и для выбора правил robots.txt
+	IgnoreRobots bool          // Не загружать и не соблюдать robots.txt
+	Timeout      time.Duration // Таймаут одного запроса
+	Deadline     time.Duration // Ограничение времени на весь запуск (0 - без ограничения)
+	Retries      int           // Сколько раз повторять запрос при временной ошибке
+	RetryBase    time.Duration // Начальная пауза между повторами
+	RetryMax     time.Duration // Максимальная пауза между повторами
+	Unordered    bool          // Выдавать результаты в порядке готовности, а не в порядке входного списка
+	MaxBody      int64         // Сколько байт тела ответа читать не больше (0 - без ограничения)
+	FullBody     bool          // Читать документ целиком, а не только до </head>
+	StrictStatus bool          // Считать ошибкой любой ответ не 2xx, а не только 4xx/5xx
+}
 
-	// Создаем HTTP клиент с таймаутом
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+// Текущие настройки парсера
+var options = Options{
+	Workers:   10,
+	PerHost:   2,
+	UserAgent: "ParallelParserDemo/1.0",
+	Timeout:   10 * time.Second,
+	Retries:   2,
+	RetryBase: 500 * time.Millisecond,
+	RetryMax:  10 * time.Second,
+	MaxBody:   2 << 20,
+}
 
-	// Выполняем HTTP запрос
-	resp, err := client.Get(url)
-	if err != nil {
-		return ParseResult{
-			URL:     url,
-			Title:   "",
-			Error:   err,
-			Elapsed: time.Since(start),
+// Общий HTTP клиент: переиспользует соединения между запросами
+var httpClient = &http.Client{Timeout: options.Timeout}
+
+// Глобальный кеш robots.txt (создается в main после разбора флагов)
+var robots *RobotsCache
+
+// Дисковый кеш страниц (nil - кеш выключен)
+var pageCache *HTTPCache
+
+// Пауза между запросами к хосту: большее из -host-delay и Crawl-delay из robots.txt
+func hostDelay(rawURL string) time.Duration {
+	delay := options.HostDelay
+	if robots != nil {
+		if crawlDelay := robots.CrawlDelay(rawURL); crawlDelay > delay {
+			delay = crawlDelay
 		}
 	}
-	defer resp.Body.Close()
+	return delay
+}
 
-	// Читаем тело ответа
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+// Функция для обработки одного URL: проверка robots.txt, затем парсинг
+func parseURL(ctx context.Context, url string) ParseResult {
+	if ctx.Err() != nil {
+		return cancelledResult(url)
+	}
+
+	if robots != nil && !robots.Allowed(ctx, url) {
 		return ParseResult{
-			URL:     url,
-			Title:   "",
-			Error:   err,
-			Elapsed: time.Since(start),
+			URL:    url,
+			Status: StatusBlocked,
 		}
 	}
 
-	// Ищем заголовок в HTML
-	titleRegex := regexp.MustCompile(`<title[^>]*>([^<]*)</title>`)
-	matches := titleRegex.FindStringSubmatch(string(body))
+	return parseWithRetry(ctx, url)
+}
+
+// Результат для URL, который не успели обработать
+func cancelledResult(url string) ParseResult {
+	return ParseResult{
+		URL:    url,
+		Status: StatusCancelled,
+	}
+}
 
-	var title string
-	if len(matches) > 1 {
-		title = strings.TrimSpace(matches[1])
-	} else {
-		title = "Заголовок не найден"
+// Результат с ошибкой: если запуск уже отменен, это не ошибка сайта, а отмена
+func errorResult(ctx context.Context, url string, err error, start time.Time) ParseResult {
+	status := StatusError
+	if ctx.Err() != nil {
+		status = StatusCancelled
 	}
 
 	return ParseResult{
 		URL:     url,
-		Title:   title,
-		Error:   nil,
+		Status:  status,
+		Error:   err,
 		Elapsed: time.Since(start),
 	}
 }
 
-// Последовательная версия парсера (медленная)
-func parseSequential(urls []string) []ParseResult {
-	fmt.Println("🐌 Запуск последовательного парсинга...")
+// Обертка над io.Reader, которая считает прочитанные байты
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Открытая страница: ответ сервера с еще не прочитанным телом или запись из кеша
+type openedPage struct {
+	resp   *http.Response // Ответ сервера (nil - страница взята из кеша)
+	cached *cacheEntry    // Запись кеша, если страница из кеша
+	source string         // Откуда страница: hit, revalidated, miss ("" - кеш выключен)
+}
+
+// Функция для запроса страницы с учетом кеша: свежая запись используется без запроса,
+// устаревшая проверяется условным запросом. Если вместо страницы получилась ошибка,
+// возвращается готовый результат с ней. Тело ответа закрывает вызывающий.
+func openPage(ctx context.Context, url string, start time.Time) (openedPage, *ParseResult) {
+	var cached *cacheEntry
+	if pageCache != nil {
+		cached = pageCache.Lookup(url, options.FullBody)
+		if cached != nil && pageCache.Fresh(cached) {
+			pageCache.count(CacheHit)
+			return openedPage{cached: cached, source: CacheHit}, nil
+		}
+	}
+
+	// Запрос привязан к контексту: отмена прерывает и соединение, и чтение тела
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		result := errorResult(ctx, url, err, start)
+		return openedPage{}, &result
+	}
+	req.Header.Set("User-Agent", options.UserAgent)
+	if cached != nil {
+		pageCache.Revalidate(req, cached)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		result := errorResult(ctx, url, err, start)
+		return openedPage{}, &result
+	}
+
+	// Страница не изменилась: сервер прислал только заголовки, тело берем из кеша
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		if pageCache.Store(cached) != nil {
+			logf("⚠️  Не удалось обновить запись кеша для %s\n", url)
+		}
+		pageCache.count(CacheRevalidated)
+		return openedPage{cached: cached, source: CacheRevalidated}, nil
+	}
+
+	// Ответ с ошибкой: дочитываем немного тела, чтобы соединение можно было переиспользовать
+	if isErrorStatus(resp.StatusCode) {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		statusErr := &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+		result := errorResult(ctx, url, statusErr, start)
+		applyResponse(&result, resp)
+		return openedPage{}, &result
+	}
+
+	page := openedPage{resp: resp}
+	if pageCache != nil {
+		page.source = CacheMiss
+		pageCache.count(CacheMiss)
+	}
+	return page, nil
+}
+
+// Функция для сохранения прочитанной страницы в кеш (если кеш включен и ответ можно хранить).
+// Тело, обрезанное по -max-body, не сохраняется: оно не годится ни для какого режима.
+func storePage(url string, resp *http.Response, raw []byte, truncated string) {
+	if pageCache == nil || !cacheable(resp) || truncated == TruncatedMaxBody {
+		return
+	}
+
+	entry := &cacheEntry{
+		URL:          url,
+		FinalURL:     resp.Request.URL.String(),
+		StatusCode:   resp.StatusCode,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Complete:     truncated == TruncatedNone,
+		Body:         raw,
+	}
+	if err := pageCache.Store(entry); err != nil {
+		logf("⚠️  Не удалось сохранить %s в кеш: %v\n", url, err)
+	}
+}
+
+// Функция для парсинга заголовка страницы
+func parsePageTitle(ctx context.Context, url string) ParseResult {
 	start := time.Now()
 
+	opened, failed := openPage(ctx, url, start)
+	if failed != nil {
+		return *failed
+	}
+	if opened.resp == nil {
+		return cachedResult(ctx, url, opened.cached, opened.source, start)
+	}
+	resp := opened.resp
+	defer resp.Body.Close()
+
+	// Тело сохраняем для кеша и для извлечения полей по правилам
+	fields := extractRules.For(url)
+	keep := (pageCache != nil && cacheable(resp)) || len(fields) > 0
+	// Ссылки разрешаем относительно адреса после редиректов
+	page, err := readPage(resp.Body, resp.Header.Get("Content-Type"), resp.ContentLength, resp.Request.URL, keep)
+	if err != nil {
+		return errorResult(ctx, url, err, start)
+	}
+	storePage(url, resp, page.raw, page.truncated)
+
+	result := ParseResult{
+		URL:       url,
+		PageMeta:  page.meta,
+		Status:    StatusOK,
+		Error:     nil,
+		Elapsed:   time.Since(start),
+		Bytes:     page.bytes,
+		Truncated: page.truncated,
+		Cache:     opened.source,
+		Fields:    extractFields(fields, url, resp.Header.Get("Content-Type"), page.raw, page.meta.Charset),
+	}
+	applyResponse(&result, resp)
+	return result
+}
+
+// Результат для страницы из кеша: разбираем сохраненное тело, из сети ничего не читаем
+func cachedResult(ctx context.Context, url string, entry *cacheEntry, source string, start time.Time) ParseResult {
+	base, err := neturl.Parse(entry.FinalURL)
+	if err != nil {
+		return errorResult(ctx, url, err, start)
+	}
+	page, err := readPage(bytes.NewReader(entry.Body), entry.ContentType, int64(len(entry.Body)), base, false)
+	if err != nil {
+		return errorResult(ctx, url, err, start)
+	}
+
+	result := ParseResult{
+		URL:      url,
+		PageMeta: page.meta,
+		Status:   StatusOK,
+		Elapsed:  time.Since(start),
+		Cache:    source,
+		Fields:   extractFields(extractRules.For(url), url, entry.ContentType, entry.Body, page.meta.Charset),
+	}
+	applyCachedResponse(&result, entry)
+	return result
+}
+
+// Последовательная версия парсера (медленная).
+// Возвращает результаты и реальное время работы.
+func parseSequential(ctx context.Context, urls []string) ([]ParseResult, time.Duration) {
+	logf("🐌 Запуск последовательного парсинга...\n")
+	start := time.Now()
+	p := startProgress("Последовательный", len(urls), len(urls))
+
 	var results []ParseResult
+	lastHit := make(map[string]time.Time)
 	for _, url := range urls {
-		fmt.Printf("   Парсинг: %s\n", url)
-		result := parsePageTitle(url)
+		// После отмены оставшиеся URL только помечаем
+		if ctx.Err() != nil {
+			results = append(results, cancelledResult(url))
+			continue
+		}
+
+		waitForHost(ctx, lastHit, url, hostDelay(url))
+		if !p.Active() {
+			logf("   Парсинг: %s\n", url)
+		}
+		p.Begin(url)
+		result := parseURL(ctx, url)
+		if result.Attempts > 1 {
+			// Последний повтор ушел позже: паузу до следующего запроса к хосту считаем от него
+			lastHit[hostKey(url)] = time.Now()
+		}
+		p.Finish(result)
 		results = append(results, result)
 	}
+	p.Stop()
 
 	elapsed := time.Since(start)
-	fmt.Printf("✅ Последовательный парсинг завершен за: %v\n\n", elapsed)
+	logf("✅ Последовательный парсинг завершен за: %v\n\n", elapsed)
 
-	return results
+	return results, elapsed
 }
 
-// Параллельная версия парсера (быстрая)
-func parseParallel(urls []string) []ParseResult {
-	fmt.Println("🚀 Запуск параллельного парсинга...")
+// Параллельная версия парсера (быстрая): пул воркеров с ограничениями по хостам.
+// Возвращает результаты и реальное время работы.
+func parseParallel(ctx context.Context, urls []string) ([]ParseResult, time.Duration) {
+	logf("🚀 Запуск параллельного парсинга (воркеров: %d, на хост: %d)...\n",
+		options.Workers, options.PerHost)
 	start := time.Now()
+	p := startProgress("Параллельный", len(urls), len(urls))
 
-	// Канал для сбора результатов
-	resultsChan := make(chan ParseResult, len(urls))
+	sched := NewHostScheduler(urls, options.PerHost, hostDelay)
+	results, stats := runWorkerPool(ctx, urls, options.Workers, sched, func(ctx context.Context, url string) ParseResult {
+		if !p.Active() {
+			logf("   Парсинг: %s (в горутине)\n", url)
+		}
+		return parseURL(ctx, url)
+	})
+	p.Stop()
 
-	// WaitGroup для ожидания завершения всех горутин
-	var wg sync.WaitGroup
+	elapsed := time.Since(start)
+	logf("✅ Параллельный парсинг завершен за: %v\n", elapsed)
+	printPoolStats(stats)
 
-	// Запускаем горутину для каждого URL
-	for _, url := range urls {
-		wg.Add(1) // Увеличиваем счетчик ожидаемых горутин
+	return results, elapsed
+}
 
-		go func(url string) {
-			defer wg.Done() // Уменьшаем счетчик при завершении горутины
+// Сводка по запуску для сравнения производительности
+type RunSummary struct {
+	Wall time.Duration // Реальное время от начала до конца запуска
+	Work time.Duration // Сумма времени обработки всех URL
+}
 
-			fmt.Printf("   Парсинг: %s (в горутине)\n", url)
-			result := parsePageTitle(url)
-			resultsChan <- result // Отправляем результат в канал
-		}(url)
+// Функция для подсчета сводки: время работы складываем по результатам
+func summarize(results []ParseResult, wall time.Duration) RunSummary {
+	summary := RunSummary{Wall: wall}
+	for _, result := range results {
+		summary.Work += result.Elapsed
 	}
+	return summary
+}
 
-	// Запускаем горутину для закрытия канала после завершения всех рабочих горутин
-	go func() {
-		wg.Wait()          // Ждем завершения всех горутин
-		close(resultsChan) // Закрываем канал
-	}()
-
-	// Собираем результаты из канала
-	var results []ParseResult
-	for result := range resultsChan {
-		results = append(results, result)
+// Эффективная конкурентность: сколько URL в среднем обрабатывалось одновременно
+func (s RunSummary) Concurrency() float64 {
+	if s.Wall <= 0 {
+		return 0
 	}
+	return float64(s.Work) / float64(s.Wall)
+}
 
-	elapsed := time.Since(start)
-	fmt.Printf("✅ Параллельный парсинг завершен за: %v\n\n", elapsed)
+// Функция для вывода сравнения производительности.
+// Ускорение считается по реальному времени: сумма времени запросов
+// у параллельного парсинга не меньше, чем у последовательного.
+func printComparison(sequential, parallel RunSummary) {
+	fmt.Println("📈 Сравнение производительности:")
+	fmt.Println("=================================")
+
+	fmt.Printf("Последовательный: реальное время %v, суммарная работа %v, конкурентность %.2f\n",
+		sequential.Wall.Round(time.Millisecond), sequential.Work.Round(time.Millisecond), sequential.Concurrency())
+	fmt.Printf("Параллельный:     реальное время %v, суммарная работа %v, конкурентность %.2f\n",
+		parallel.Wall.Round(time.Millisecond), parallel.Work.Round(time.Millisecond), parallel.Concurrency())
 
-	return results
+	if parallel.Wall > 0 {
+		speedup := float64(sequential.Wall) / float64(parallel.Wall)
+		fmt.Printf("Ускорение: %.2fx\n", speedup)
+	}
 }
 
 // Функция для вывода результатов
@@ -135,64 +413,612 @@ func printResults(results []ParseResult, title string) {
 	fmt.Println(strings.Repeat("-", 80))
 
 	for _, result := range results {
-		if result.Error != nil {
-			fmt.Printf("❌ %s: Ошибка - %v (время: %v)\n",
-				result.URL, result.Error, result.Elapsed)
+		if result.Status == StatusBlocked {
+			fmt.Printf("🚫 %s: Пропущен - запрещено robots.txt\n", result.URL)
+		} else if result.Status == StatusCancelled {
+			fmt.Printf("⏹️  %s: Отменен - не успел завершиться\n", result.URL)
+		} else if result.Error != nil {
+			fmt.Printf("❌ %s: Ошибка [%s] - %v (попыток: %d, время: %v)\n",
+				result.URL, result.ErrorClass, result.Error, result.Attempts, result.Elapsed)
 		} else {
 			fmt.Printf("✅ %s: %s (время: %v)\n",
 				result.URL, result.Title, result.Elapsed)
+			if len(result.Redirects) > 0 {
+				fmt.Printf("   ↪️  Редиректы: %s\n", formatRedirects(result.Redirects))
+			}
+			if result.ContentType != "" && result.ContentType != ContentHTML {
+				fmt.Printf("   📄 Тип содержимого: %s\n", result.ContentType)
+			}
+			if result.Truncated == TruncatedMaxBody {
+				fmt.Printf("   ✂️  Тело обрезано: прочитано %s из-за -max-body\n", formatBytes(result.Bytes))
+			}
+			for _, name := range fieldNames(result.Fields) {
+				fmt.Printf("   🧩 %s: %s\n", name, fieldValue(result.Fields[name]))
+			}
+			if showDetails {
+				printMeta(result.PageMeta)
+				for _, name := range sortedKeys(result.Headers) {
+					fmt.Printf("   📨 %s: %s\n", name, result.Headers[name])
+				}
+				if result.Words > 0 {
+					fmt.Printf("   📚 Слов: %d, язык текста: %s\n", result.Words, valueOr(result.TextLanguage, "не определен"))
+				}
+			}
 		}
 	}
+	printFailureSummary(results)
+	printBodySummary(results)
 	fmt.Println()
 }
 
+// Функция для вывода подробных метаданных страницы
+func printMeta(meta PageMeta) {
+	if meta.Description != "" {
+		fmt.Printf("   📝 Описание: %s\n", meta.Description)
+	}
+	if meta.Canonical != "" {
+		fmt.Printf("   🔗 Canonical: %s\n", meta.Canonical)
+	}
+	if meta.Language != "" {
+		fmt.Printf("   🌐 Язык: %s\n", meta.Language)
+	}
+	if meta.Charset != "" {
+		fmt.Printf("   🔤 Кодировка: %s (%s)\n", meta.Charset, meta.CharsetFrom)
+	}
+	for _, key := range sortedKeys(meta.OpenGraph) {
+		fmt.Printf("   📣 %s: %s\n", key, meta.OpenGraph[key])
+	}
+	for _, key := range sortedKeys(meta.Twitter) {
+		fmt.Printf("   🐦 %s: %s\n", key, meta.Twitter[key])
+	}
+	for _, heading := range meta.Headings {
+		fmt.Printf("   🔠 h1: %s\n", heading)
+	}
+	fmt.Printf("   🔗 Ссылок на странице: %d\n", len(meta.Links))
+}
+
+// Функция для получения отсортированных ключей словаря
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Функция для подстановки значения по умолчанию вместо пустой строки
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// Показывать ли подробные метаданные страниц
+var showDetails bool
+
+// Вызывается для каждого результата пула воркеров сразу после его получения
+// (используется для потокового вывода, например -format jsonl)
+var onResult func(ParseResult)
+
+// Демонстрационный список URL (используется, если источники не указаны)
+var defaultURLs = []string{
+	"https://httpbin.org/html",
+	"https://httpbin.org/json",
+	"https://httpbin.org/xml",
+	"https://httpbin.org/robots.txt",
+	"https://httpbin.org/user-agent",
+}
+
 func main() {
-	fmt.Println("🎯 Демонстрация многопоточности в Go")
-	fmt.Println("=====================================")
-	fmt.Println()
+	// Источники URL
+	var urlsFile string
+	var readStdin bool
+	var sitemap string
+
+	// Формат вывода результатов
+	var format string
+
+	// Проверка ссылок на страницах
+	var checkLinksMode bool
+
+	// Какие заголовки ответа сохранять
+	headersSpec := strings.Join(captureHeaders, ",")
+
+	// Заголовки запросов, авторизация, cookies и прокси
+	var requestHeaders, authSpecs listFlag
+	var clientOpts ClientOptions
+
+	// Файл правил извлечения полей
+	var rulesFile string
+
+	// Режим HTTP сервиса: адрес, на котором принимать задания
+	var serveAddr string
+
+	// Стратегия параллельного парсинга и сравнение всех стратегий
+	strategyName := "pool"
+	var compareAll bool
+
+	// Конвейер из этапов вместо пула воркеров
+	var pipelineMode bool
+	var stagesSpec string
+
+	// Дисковый кеш страниц
+	var cacheDir string
+	var cacheMaxAge time.Duration
+	var cacheBypass bool
+
+	// Локальный тестовый сервер вместо интернета
+	var offline bool
+	var offlineAddr string
+	fixtureOpts := defaultFixtureOptions
 
-	// Список URL для парсинга
-	urls := []string{
-		"https://httpbin.org/html",
-		"https://httpbin.org/json",
-		"https://httpbin.org/xml",
-		"https://httpbin.org/robots.txt",
-		"https://httpbin.org/user-agent",
+	// Режим обхода сайта
+	var crawlMode bool
+	var allowDomains, allowPrefix string
+	crawlOpts := CrawlOptions{MaxDepth: 2, MaxPages: 100, CheckpointEvery: 10 * time.Second}
+
+	flag.StringVar(&urlsFile, "file", "", "Файл со списком URL, по одному на строку (\"-\" - stdin)")
+	flag.BoolVar(&readStdin, "stdin", false, "Читать URL из stdin, по одному на строку")
+	flag.StringVar(&sitemap, "sitemap", "", "URL или путь к sitemap.xml (поддерживаются индексы sitemap)")
+	flag.BoolVar(&showDetails, "details", false, "Показать подробные метаданные страниц")
+	flag.StringVar(&format, "format", "text", "Формат вывода: text, json, jsonl, csv, table")
+	flag.StringVar(&rulesFile, "rules", "", "JSON файл с правилами извлечения полей (css, regex, json) по шаблонам URL")
+	flag.StringVar(&serveAddr, "serve", "", "Запустить парсер как HTTP сервис на адресе, например 127.0.0.1:8080 (POST /parse, GET /jobs/{id})")
+	flag.BoolVar(&crawlMode, "crawl", false, "Обходить сайт по ссылкам, начиная с указанных URL")
+	flag.BoolVar(&checkLinksMode, "check-links", false, "Проверить все ссылки на страницах (с -crawl - на всех обойденных) и вывести битые")
+	flag.IntVar(&crawlOpts.MaxDepth, "depth", crawlOpts.MaxDepth, "Максимальная глубина обхода")
+	flag.IntVar(&crawlOpts.MaxPages, "max-pages", crawlOpts.MaxPages, "Сколько страниц можно обработать при обходе (0 - без ограничения)")
+	flag.StringVar(&allowDomains, "allow-domains", "", "Домены для обхода через запятую (по умолчанию - домены стартовых URL)")
+	flag.StringVar(&allowPrefix, "allow-prefix", "", "Префиксы URL для обхода через запятую")
+	flag.StringVar(&crawlOpts.Checkpoint, "checkpoint", "", "Файл контрольной точки обхода: состояние сохраняется, а прерванный обход продолжается с того же места")
+	flag.DurationVar(&crawlOpts.CheckpointEvery, "checkpoint-every", crawlOpts.CheckpointEvery, "Как часто сохранять контрольную точку обхода")
+	flag.IntVar(&options.Workers, "workers", options.Workers, "Количество воркеров для параллельного парсинга")
+	flag.IntVar(&options.PerHost, "per-host", options.PerHost, "Максимум одновременных запросов к одному хосту (0 - без ограничения)")
+	flag.DurationVar(&options.HostDelay, "host-delay", options.HostDelay, "Минимальная пауза между запросами к одному хосту")
+	flag.StringVar(&options.UserAgent, "user-agent", options.UserAgent, "User-Agent для запросов и правил robots.txt")
+	flag.Var(&requestHeaders, "header", "Дополнительный заголовок запросов \"Имя: значение\" (можно указать несколько раз)")
+	flag.Var(&authSpecs, "auth", "Авторизация для хоста: хост=логин:пароль или хост=bearer:токен; секрет может быть env:ПЕРЕМЕННАЯ (можно указать несколько раз)")
+	flag.StringVar(&clientOpts.CookiesFile, "cookies", "", "Файл cookies в формате Netscape (как выгружают браузеры и curl)")
+	flag.StringVar(&clientOpts.Proxy, "proxy", "", "Прокси http://, https:// или socks5://хост:порт (по умолчанию - из HTTP_PROXY/HTTPS_PROXY)")
+	flag.BoolVar(&options.IgnoreRobots, "ignore-robots", options.IgnoreRobots, "Не соблюдать robots.txt")
+	flag.DurationVar(&options.Timeout, "timeout", options.Timeout, "Таймаут одного запроса")
+	flag.DurationVar(&options.Deadline, "deadline", options.Deadline, "Ограничение времени на весь запуск, например 30s (0 - без ограничения)")
+	flag.IntVar(&options.Retries, "retries", options.Retries, "Сколько раз повторять запрос при временной ошибке")
+	flag.DurationVar(&options.RetryBase, "retry-base", options.RetryBase, "Начальная пауза между повторами (растет экспоненциально)")
+	flag.DurationVar(&options.RetryMax, "retry-max", options.RetryMax, "Максимальная пауза между повторами")
+	flag.Int64Var(&options.MaxBody, "max-body", options.MaxBody, "Сколько байт тела ответа читать не больше (0 - без ограничения)")
+	flag.BoolVar(&options.FullBody, "full-body", options.FullBody, "Читать страницы целиком, а не только до </head> (включается с -crawl и -details)")
+	flag.BoolVar(&options.StrictStatus, "strict-status", options.StrictStatus, "Считать ошибкой любой ответ не 2xx (по умолчанию - только 4xx и 5xx)")
+	flag.StringVar(&headersSpec, "headers", headersSpec, "Заголовки ответа, которые сохранять в результатах, через запятую (пусто - никакие)")
+	flag.BoolVar(&options.Unordered, "unordered", options.Unordered, "Выдавать результаты по мере готовности, а не в порядке входного списка")
+	flag.StringVar(&progressMode, "progress", progressMode, "Ход работы: auto (от 50 URL), tty (перерисовка на месте), plain (строка раз в 5 секунд), off")
+	flag.StringVar(&strategyName, "strategy", strategyName, "Стратегия параллельного парсинга: sequential, goroutines, pool, semaphore, errgroup, pipeline")
+	flag.BoolVar(&compareAll, "compare-all", false, "Запустить все стратегии на локальном тестовом сервере и вывести сравнительную таблицу")
+	flag.BoolVar(&pipelineMode, "pipeline", false, "Параллельный парсинг конвейером: fetch -> parse -> enrich -> sink (то же, что -strategy pipeline)")
+	flag.StringVar(&stagesSpec, "stages", "", "Воркеры и очереди этапов конвейера, например fetch=20:40,parse=4:8,enrich=2:8")
+	flag.StringVar(&cacheDir, "cache-dir", "", "Каталог дискового кеша страниц (пусто - кеш выключен)")
+	flag.DurationVar(&cacheMaxAge, "cache-max-age", time.Hour, "Сколько запись кеша используется без запроса к серверу (потом - проверка через 304)")
+	flag.BoolVar(&cacheBypass, "cache-bypass", false, "Не брать страницы из кеша, но обновить его свежими ответами")
+	flag.BoolVar(&offline, "offline", false, "Запустить локальный тестовый сервер и парсить его страницы (без интернета)")
+	flag.StringVar(&offlineAddr, "offline-addr", "127.0.0.1:0", "Адрес локального тестового сервера")
+	flag.IntVar(&fixtureOpts.Pages, "offline-pages", fixtureOpts.Pages, "Сколько страниц тестового сервера парсить")
+	flag.DurationVar(&fixtureOpts.Latency, "offline-latency", fixtureOpts.Latency, "Задержка ответа тестового сервера")
+	flag.DurationVar(&fixtureOpts.Jitter, "offline-jitter", fixtureOpts.Jitter, "Случайная добавка к задержке тестового сервера")
+	flag.IntVar(&fixtureOpts.PageSize, "offline-size", fixtureOpts.PageSize, "Размер страниц тестового сервера в байтах")
+	flag.StringVar(&fixtureOpts.Encoding, "offline-encoding", fixtureOpts.Encoding, "Кодирование ответов тестового сервера: identity, gzip, chunked")
+	flag.StringVar(&fixtureOpts.Charset, "offline-charset", fixtureOpts.Charset, "Кодировка страниц тестового сервера: utf-8, windows-1251, koi8-r")
+	flag.StringVar(&fixtureOpts.Declare, "offline-declare", fixtureOpts.Declare, "Где тестовый сервер объявляет кодировку: header, meta, none")
+	flag.Float64Var(&fixtureOpts.ErrorRate, "offline-errors", fixtureOpts.ErrorRate, "Доля ответов тестового сервера с ошибкой 5xx/429 (от 0 до 1)")
+	flag.Float64Var(&fixtureOpts.RedirectRate, "offline-redirects", fixtureOpts.RedirectRate, "Доля страниц тестового сервера с цепочкой редиректов (от 0 до 1)")
+	flag.IntVar(&fixtureOpts.Redirects, "offline-hops", fixtureOpts.Redirects, "Длина цепочки редиректов тестового сервера")
+	flag.Float64Var(&fixtureOpts.BrokenRate, "offline-broken", fixtureOpts.BrokenRate, "Доля страниц тестового сервера с битыми ссылками и циклом редиректов (от 0 до 1)")
+	flag.Parse()
+
+	// В машиночитаемых форматах stdout занят результатами, сообщения идут в stderr
+	var writer ResultWriter
+	if format != "text" {
+		var err error
+		writer, err = newResultWriter(format, os.Stdout)
+		if err != nil {
+			fmt.Printf("❌ Ошибка: %v\n", err)
+			os.Exit(1)
+		}
+
+		logOut = os.Stderr
+		onResult = func(result ParseResult) {
+			if err := writer.Write(result); err != nil {
+				logf("❌ Ошибка вывода результата: %v\n", err)
+			}
+		}
 	}
 
-	fmt.Printf("📝 Парсим %d URL...\n\n", len(urls))
+	if options.Workers < 1 {
+		logf("❌ Ошибка: -workers должно быть не меньше 1\n")
+		os.Exit(1)
+	}
+	if options.PerHost < 0 || options.HostDelay < 0 || options.Deadline < 0 || options.Retries < 0 {
+		logf("❌ Ошибка: -per-host, -host-delay, -deadline и -retries не могут быть отрицательными\n")
+		os.Exit(1)
+	}
+	if !contains(progressModes, progressMode) {
+		logf("❌ Ошибка: неизвестный режим -progress %q, доступны: %v\n", progressMode, progressModes)
+		os.Exit(1)
+	}
+	if options.MaxBody < 0 {
+		logf("❌ Ошибка: -max-body не может быть отрицательным\n")
+		os.Exit(1)
+	}
+	if options.RetryBase <= 0 || options.RetryMax < options.RetryBase {
+		logf("❌ Ошибка: -retry-base должно быть больше 0 и не больше -retry-max\n")
+		os.Exit(1)
+	}
 
-	// Запускаем последовательный парсинг
-	sequentialResults := parseSequential(urls)
+	// Сравнение стратегий: горутина на каждый URL не соблюдает ограничения по хостам,
+	// поэтому запускаем его только на своем тестовом сервере
+	if compareAll {
+		if urlsFile != "" || readStdin || sitemap != "" || flag.NArg() > 0 {
+			logf("❌ Ошибка: -compare-all работает только с локальным тестовым сервером, источники URL указывать нельзя\n")
+			os.Exit(1)
+		}
+		if writer != nil || crawlMode || checkLinksMode || cacheDir != "" {
+			logf("❌ Ошибка: -compare-all нельзя использовать с -format, -crawl, -check-links и -cache-dir\n")
+			os.Exit(1)
+		}
+		offline = true
+	}
 
-	// Запускаем параллельный парсинг
-	parallelResults := parseParallel(urls)
+	// Сервис получает URL в заданиях, а результаты отдает по HTTP
+	if serveAddr != "" {
+		if urlsFile != "" || readStdin || sitemap != "" || flag.NArg() > 0 {
+			logf("❌ Ошибка: с -serve URL передаются в POST /parse, источники URL указывать нельзя\n")
+			os.Exit(1)
+		}
+		if writer != nil || crawlMode || checkLinksMode || compareAll || flagSet("strategy") || pipelineMode {
+			logf("❌ Ошибка: -serve нельзя использовать с -format, -crawl, -check-links, -compare-all, -strategy и -pipeline\n")
+			os.Exit(1)
+		}
+		// Сервис без авторизации: любой, кто до него дотянется, получит запросы
+		// с нашими заголовками, паролями и cookies на свои URL
+		if (len(requestHeaders) > 0 || len(authSpecs) > 0 || clientOpts.CookiesFile != "") && !isLoopbackAddr(serveAddr) {
+			logf("❌ Ошибка: с -header, -auth и -cookies сервис можно запускать только на локальном адресе (127.0.0.1, ::1, localhost)\n")
+			os.Exit(1)
+		}
+	}
 
-	// Выводим результаты
-	printResults(sequentialResults, "Результаты последовательного парсинга")
-	printResults(parallelResults, "Результаты параллельного парсинга")
+	if offline {
+		if fixtureOpts.Pages < 1 || fixtureOpts.Latency < 0 || fixtureOpts.Jitter < 0 || fixtureOpts.PageSize < 0 || fixtureOpts.Redirects < 0 {
+			logf("❌ Ошибка: -offline-pages должно быть не меньше 1, остальные параметры -offline-* не могут быть отрицательными\n")
+			os.Exit(1)
+		}
+		if fixtureOpts.ErrorRate < 0 || fixtureOpts.ErrorRate > 1 || fixtureOpts.RedirectRate < 0 || fixtureOpts.RedirectRate > 1 ||
+			fixtureOpts.BrokenRate < 0 || fixtureOpts.BrokenRate > 1 {
+			logf("❌ Ошибка: -offline-errors, -offline-redirects и -offline-broken должны быть от 0 до 1\n")
+			os.Exit(1)
+		}
+		if !contains(fixtureEncodings, fixtureOpts.Encoding) {
+			logf("❌ Ошибка: неизвестное -offline-encoding %q, доступны: %v\n", fixtureOpts.Encoding, fixtureEncodings)
+			os.Exit(1)
+		}
+		if !contains(fixtureCharsets, fixtureOpts.Charset) || !contains(fixtureDeclares, fixtureOpts.Declare) {
+			logf("❌ Ошибка: -offline-charset может быть %v, -offline-declare - %v\n", fixtureCharsets, fixtureDeclares)
+			os.Exit(1)
+		}
+	}
 
-	// Сравниваем производительность
-	fmt.Println("📈 Сравнение производительности:")
-	fmt.Println("=================================")
+	captureHeaders = parseHeaderList(headersSpec)
+
+	// Настройки этапов читаем после -workers: от него зависит число воркеров загрузки
+	stageConfigs := defaultStageConfigs()
+	if err := parseStageConfigs(stagesSpec, stageConfigs); err != nil {
+		logf("❌ Ошибка в -stages: %v\n", err)
+		os.Exit(1)
+	}
 
-	// Вычисляем общее время для каждого метода
-	var sequentialTime, parallelTime time.Duration
-	for _, result := range sequentialResults {
-		sequentialTime += result.Elapsed
+	// -pipeline - короткая запись для -strategy pipeline
+	if pipelineMode {
+		if flagSet("strategy") && strategyName != "pipeline" {
+			logf("❌ Ошибка: -pipeline нельзя использовать вместе с -strategy %s\n", strategyName)
+			os.Exit(1)
+		}
+		strategyName = "pipeline"
+	}
+	strategies := newStrategies(stageConfigs)
+	strategy, err := findStrategy(strategies, strategyName)
+	if err != nil {
+		logf("❌ Ошибка в -strategy: %v\n", err)
+		os.Exit(1)
+	}
+	// Обход сайта идет уровнями через пул воркеров, другие стратегии ему не подходят
+	if crawlMode && strategy.Name() != "pool" {
+		logf("❌ Ошибка: -strategy %s нельзя использовать вместе с -crawl\n", strategy.Name())
+		os.Exit(1)
 	}
-	for _, result := range parallelResults {
-		parallelTime += result.Elapsed
+
+	if rulesFile != "" {
+		var err error
+		extractRules, err = LoadRules(rulesFile)
+		if err != nil {
+			logf("❌ Ошибка в -rules: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	fmt.Printf("Последовательный: %v\n", sequentialTime)
-	fmt.Printf("Параллельный: %v\n", parallelTime)
+	// Ссылки и <h1> находятся в <body>: для обхода и подробного вывода документ нужен целиком.
+	// Конвейеру он тоже нужен целиком - этап enrich считает слова во всем тексте,
+	// а при сравнении стратегий все они должны читать одинаково.
+	// Правила извлечения могут искать поля в любой части страницы, а проверке ссылок нужны все ссылки.
+	if crawlMode || showDetails || strategy.Name() == "pipeline" || compareAll || extractRules != nil || checkLinksMode {
+		options.FullBody = true
+	}
 
-	if parallelTime > 0 {
-		speedup := float64(sequentialTime) / float64(parallelTime)
-		fmt.Printf("Ускорение: %.2fx\n", speedup)
+	// Параллельный парсинг выбранной стратегией (по умолчанию - пул воркеров)
+	runParallel := strategy.Run
+
+	httpClient.Timeout = options.Timeout
+	if clientOpts.Headers, err = parseHeaders(requestHeaders); err != nil {
+		logf("❌ Ошибка в -header: %v\n", err)
+		os.Exit(1)
+	}
+	if clientOpts.Auth, err = parseAuth(authSpecs); err != nil {
+		logf("❌ Ошибка в -auth: %v\n", err)
+		os.Exit(1)
+	}
+	if err := configureClient(httpClient, clientOpts); err != nil {
+		logf("❌ Ошибка настройки HTTP клиента: %v\n", err)
+		os.Exit(1)
+	}
+	if !options.IgnoreRobots {
+		robots = NewRobotsCache(httpClient, options.UserAgent)
+	}
+	if cacheDir != "" {
+		if cacheMaxAge < 0 {
+			logf("❌ Ошибка: -cache-max-age не может быть отрицательным\n")
+			os.Exit(1)
+		}
+		var err error
+		pageCache, err = NewHTTPCache(cacheDir, cacheMaxAge, cacheBypass)
+		if err != nil {
+			logf("❌ Ошибка при создании кеша: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Ctrl-C (и SIGTERM) отменяет все запросы, которые выполняются прямо сейчас
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if options.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Deadline)
+		defer cancel()
+	}
+
+	// После первой отмены возвращаем обычное поведение: повторный Ctrl-C завершит программу сразу
+	finished := make(chan struct{})
+	defer close(finished)
+
+	go func() {
+		<-ctx.Done()
+		stop()
+
+		// Контекст отменен при обычном выходе из main - сообщать не о чем
+		select {
+		case <-finished:
+			return
+		default:
+		}
+		logf("\n⏹️  Остановка (%v): завершаем запросы и выводим готовые результаты...\n\n", context.Cause(ctx))
+	}()
+
+	logf("🎯 Демонстрация многопоточности в Go\n")
+	logf("=====================================\n")
+	logf("\n")
+
+	// Локальный тестовый сервер: результаты не зависят от сети и повторяются на любой машине
+	var fixture *FixtureServer
+	if offline {
+		var err error
+		fixture, err = StartFixtureServer(offlineAddr, fixtureOpts)
+		if err != nil {
+			logf("❌ Ошибка запуска тестового сервера: %v\n", err)
+			os.Exit(1)
+		}
+		defer fixture.Close()
+
+		logf("🧪 Локальный тестовый сервер: %s (задержка %v±%v, ошибки %.0f%%, редиректы %.0f%%)\n",
+			fixture.URL, fixtureOpts.Latency, fixtureOpts.Jitter, fixtureOpts.ErrorRate*100, fixtureOpts.RedirectRate*100)
+
+		// Все страницы на одном хосте: если -per-host не задан явно, не ограничиваем
+		// одновременные запросы к своему серверу, иначе сравнение упрется в -per-host
+		if !flagSet("per-host") {
+			options.PerHost = 0
+		}
+		logf("\n")
+	}
+
+	// Режим сервиса: работает до Ctrl-C
+	if serveAddr != "" {
+		if err := serve(ctx, serveAddr); err != nil {
+			logf("❌ Ошибка сервиса: %v\n", err)
+			os.Exit(1)
+		}
+		printCacheStats(pageCache)
+		return
+	}
+
+	// Собираем URL из всех указанных источников
+	var raw []string
+
+	if urlsFile != "" {
+		fileURLs, err := readURLFile(urlsFile)
+		if err != nil {
+			logf("❌ Ошибка при чтении файла %s: %v\n", urlsFile, err)
+			os.Exit(1)
+		}
+		raw = append(raw, fileURLs...)
+	}
+
+	if readStdin {
+		stdinURLs, err := readURLLines(os.Stdin)
+		if err != nil {
+			logf("❌ Ошибка при чтении stdin: %v\n", err)
+			os.Exit(1)
+		}
+		raw = append(raw, stdinURLs...)
+	}
+
+	if sitemap != "" {
+		sitemapURLs, err := loadSitemap(ctx, sitemap)
+		if ctx.Err() != nil {
+			logf("⏹️  Загрузка sitemap прервана\n")
+			os.Exit(1)
+		}
+		if err != nil {
+			logf("❌ Ошибка при загрузке sitemap: %v\n", err)
+			os.Exit(1)
+		}
+		logf("🗺️  Из sitemap получено %d URL\n", len(sitemapURLs))
+		raw = append(raw, sitemapURLs...)
+	}
+
+	raw = append(raw, flag.Args()...)
+
+	// Если источники не указаны, используем демонстрационный список
+	if len(raw) == 0 {
+		if fixture != nil {
+			raw = fixture.PageURLs()
+		} else {
+			raw = defaultURLs
+		}
+	}
+
+	urls, skipped := cleanURLs(raw)
+	for _, s := range skipped {
+		logf("⚠️  Пропущен %q: %s\n", s.URL, s.Reason)
+	}
+	if len(skipped) > 0 {
+		logf("\n")
+	}
+
+	if len(urls) == 0 {
+		logf("❌ Ошибка: нет ни одного корректного URL для парсинга\n")
+		os.Exit(1)
+	}
+
+	if crawlMode {
+		if crawlOpts.MaxDepth < 0 || crawlOpts.MaxPages < 0 {
+			logf("❌ Ошибка: -depth и -max-pages не могут быть отрицательными\n")
+			os.Exit(1)
+		}
+
+		crawlOpts.AllowedDomains = splitList(strings.ToLower(allowDomains))
+		crawlOpts.AllowedPrefix = splitList(allowPrefix)
+		if len(crawlOpts.AllowedDomains) == 0 && len(crawlOpts.AllowedPrefix) == 0 {
+			crawlOpts.AllowedDomains = defaultCrawlDomains(urls)
+		}
+
+		// Контрольная точка прошлого запуска: продолжаем обход с нее
+		if crawlOpts.Checkpoint != "" {
+			if crawlOpts.CheckpointEvery <= 0 {
+				logf("❌ Ошибка: -checkpoint-every должно быть больше 0\n")
+				os.Exit(1)
+			}
+			checkpoint, err := loadCheckpoint(crawlOpts.Checkpoint)
+			if err == nil && checkpoint != nil {
+				err = checkpoint.matches(urls, crawlOpts)
+			}
+			if err != nil {
+				logf("❌ Ошибка в -checkpoint: %v (удалите файл, чтобы начать обход заново)\n", err)
+				os.Exit(1)
+			}
+			crawlOpts.Resume = checkpoint
+		}
+	} else if crawlOpts.Checkpoint != "" {
+		logf("❌ Ошибка: -checkpoint работает только с -crawl\n")
+		os.Exit(1)
+	}
+
+	// Сравнение стратегий: вместо результатов страниц - таблица
+	if compareAll {
+		logf("📝 Сравниваем %d стратегий на %d URL (воркеров: %d)...\n\n", len(strategies), len(urls), options.Workers)
+		printStrategyTable(compareStrategies(ctx, strategies, urls))
+		return
 	}
 
+	// Режим проверки ссылок: страницы - из обхода или из входного списка, выводятся ссылки
+	if checkLinksMode {
+		onResult = nil
+
+		var pages []ParseResult
+		if crawlMode {
+			pages = crawl(ctx, urls, crawlOpts)
+		} else {
+			logf("📝 Парсим %d URL...\n\n", len(urls))
+			pages, _ = runParallel(ctx, urls)
+		}
+		report := checkLinks(ctx, pages)
+
+		if writer != nil {
+			for _, link := range report.Order {
+				result := report.Results[link]
+				result.Referrers = report.Referrers(link)
+				if err := writer.Write(result); err != nil {
+					logf("❌ Ошибка вывода результата: %v\n", err)
+				}
+			}
+			closeResultWriter(writer)
+			return
+		}
+		printLinkReport(report)
+		printCacheStats(pageCache)
+		return
+	}
+
+	// Режим обхода: вместо сравнения последовательного и параллельного парсинга
+	if crawlMode {
+		results := crawl(ctx, urls, crawlOpts)
+		if writer != nil {
+			printBodySummary(results)
+			printCacheStats(pageCache)
+			closeResultWriter(writer)
+			return
+		}
+		printResults(results, "Результаты обхода")
+		printCacheStats(pageCache)
+		return
+	}
+
+	logf("📝 Парсим %d URL...\n\n", len(urls))
+
+	// Для машиночитаемого вывода сравнение не нужно - только параллельный парсинг
+	if writer != nil {
+		results, _ := runParallel(ctx, urls)
+		printBodySummary(results)
+		printCacheStats(pageCache)
+		closeResultWriter(writer)
+		return
+	}
+
+	if pageCache != nil {
+		logf("⚠️  Кеш включен: параллельный парсинг возьмет страницы, сохраненные последовательным, и сравнение будет нечестным\n\n")
+	}
+
+	// Запускаем последовательный парсинг
+	sequentialResults, sequentialWall := parseSequential(ctx, urls)
+
+	// Запускаем параллельный парсинг
+	parallelResults, parallelWall := runParallel(ctx, urls)
+
+	// Выводим результаты
+	printResults(sequentialResults, "Результаты последовательного парсинга")
+	printResults(parallelResults, "Результаты параллельного парсинга")
+
+	// Сравниваем производительность
+	printComparison(summarize(sequentialResults, sequentialWall), summarize(parallelResults, parallelWall))
+	printCacheStats(pageCache)
+
 	fmt.Println()
 	fmt.Println("🎉 Ключевые концепции Go:")
 	fmt.Println("• go func() - запуск горутины")
@@ -200,3 +1026,24 @@ func main() {
 	fmt.Println("• chan - каналы для безопасной передачи данных")
 	fmt.Println("• Горутины очень дешевые - можно создавать тысячи!")
 }
+
+// Задан ли флаг явно в командной строке
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// Есть ли строка в списке
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Метаданные страницы, извлеченные из HTML
+type PageMeta struct {
+	Title       string            // <title> (для JSON, XML и текста - см. documentTitle)
+	Description string            // <meta name="description">
+	Canonical   string            // <link rel="canonical">
+	Language    string            // <html lang> или Content-Language
+	OpenGraph   map[string]string // <meta property="og:*">
+	Twitter     map[string]string // <meta name="twitter:*">
+	Headings    []string          // Тексты заголовков <h1>
+	Links       []string          // Исходящие ссылки <a href> (абсолютные, без дубликатов)
+	Charset     string            // Кодировка документа (windows-1251, koi8-r, utf-8...)
+	CharsetFrom string            // Откуда определена кодировка: bom, header, meta, sniff, default
+	ContentType string            // Тип содержимого: html, json, xml, text, other
+}
+
+// Функция для схлопывания пробелов: "  a \n b " -> "a b"
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Функция для превращения ссылки в абсолютный URL
+func resolveLink(base *url.URL, href string) (string, bool) {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return "", false
+	}
+
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+
+	resolved := ref
+	if base != nil {
+		resolved = base.ResolveReference(ref)
+	}
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return "", false
+	}
+
+	resolved.Fragment = ""
+	return resolved.String(), true
+}
+
+// Функция для извлечения метаданных из HTML-потока.
+// base - адрес страницы (после редиректов), относительно него разрешаются ссылки.
+// С headOnly чтение останавливается на </head> или <body>: заголовок, описание,
+// canonical и Open Graph уже найдены, а <h1> и ссылки не нужны.
+// Второе значение - остановились ли раньше конца документа.
+func extractMeta(r io.Reader, base *url.URL, headOnly bool) (PageMeta, bool, error) {
+	meta := PageMeta{
+		OpenGraph: make(map[string]string),
+		Twitter:   make(map[string]string),
+	}
+
+	tokenizer := newHTMLTokenizer(r)
+	seenLinks := make(map[string]bool)
+
+	var (
+		inTitle    bool            // Сейчас внутри <title>
+		titleFound bool            // Первый <title> уже найден
+		svgDepth   int             // <title> внутри <svg> - это подсказка к картинке, а не заголовок
+		h1Depth    int             // Сейчас внутри <h1>
+		h1Text     strings.Builder // Текст текущего <h1>
+	)
+
+	for {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return meta, false, err
+		}
+
+		if headOnly && !inTitle && isHeadEnd(token) {
+			return meta, true, nil
+		}
+
+		switch token.Type {
+		case textToken:
+			if inTitle {
+				meta.Title += token.Data
+			}
+			if h1Depth > 0 {
+				h1Text.WriteString(token.Data)
+			}
+
+		case startTagToken:
+			switch token.Name {
+			case "html":
+				if lang, ok := token.Attr("lang"); ok && meta.Language == "" {
+					meta.Language = strings.TrimSpace(lang)
+				}
+			case "base":
+				if href, ok := token.Attr("href"); ok {
+					if resolved, ok := resolveLink(base, href); ok {
+						base, _ = url.Parse(resolved)
+					}
+				}
+			case "svg":
+				if !token.SelfClosing {
+					svgDepth++
+				}
+			case "title":
+				if svgDepth == 0 && !titleFound {
+					inTitle = true
+				}
+			case "meta":
+				applyMetaTag(&meta, token)
+			case "link":
+				if rel, _ := token.Attr("rel"); hasToken(rel, "canonical") && meta.Canonical == "" {
+					if href, ok := token.Attr("href"); ok {
+						meta.Canonical, _ = resolveLink(base, href)
+					}
+				}
+			case "h1":
+				if h1Depth == 0 {
+					h1Text.Reset()
+				}
+				h1Depth++
+			case "a", "area":
+				if href, ok := token.Attr("href"); ok {
+					if link, ok := resolveLink(base, href); ok && !seenLinks[link] {
+						seenLinks[link] = true
+						meta.Links = append(meta.Links, link)
+					}
+				}
+			}
+
+		case endTagToken:
+			switch token.Name {
+			case "svg":
+				if svgDepth > 0 {
+					svgDepth--
+				}
+			case "title":
+				if inTitle {
+					inTitle = false
+					titleFound = true
+					meta.Title = collapseSpaces(meta.Title)
+				}
+			case "h1":
+				if h1Depth > 0 {
+					h1Depth--
+					if h1Depth == 0 {
+						if text := collapseSpaces(h1Text.String()); text != "" {
+							meta.Headings = append(meta.Headings, text)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Незакрытый <title> в конце документа
+	if inTitle {
+		meta.Title = collapseSpaces(meta.Title)
+	}
+
+	return meta, false, nil
+}
+
+// Заканчивается ли на этом токене <head> документа
+func isHeadEnd(token htmlToken) bool {
+	return (token.Type == endTagToken && token.Name == "head") ||
+		(token.Type == startTagToken && token.Name == "body")
+}
+
+// Содержит ли список через пробел нужное слово (rel="alternate canonical")
+func hasToken(list, want string) bool {
+	for _, field := range strings.Fields(list) {
+		if strings.EqualFold(field, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// Функция для разбора <meta>: description, Open Graph, Twitter, Content-Language
+func applyMetaTag(meta *PageMeta, token htmlToken) {
+	content, hasContent := token.Attr("content")
+	if !hasContent {
+		return
+	}
+	content = strings.TrimSpace(content)
+
+	name, _ := token.Attr("name")
+	property, _ := token.Attr("property")
+	httpEquiv, _ := token.Attr("http-equiv")
+	name = strings.ToLower(strings.TrimSpace(name))
+	property = strings.ToLower(strings.TrimSpace(property))
+
+	// Многие сайты путают name и property для og:/twitter: - принимаем оба
+	key := property
+	if key == "" {
+		key = name
+	}
+
+	switch {
+	case name == "description" && meta.Description == "":
+		meta.Description = content
+	case strings.HasPrefix(key, "og:"):
+		if _, exists := meta.OpenGraph[key]; !exists {
+			meta.OpenGraph[key] = content
+		}
+	case strings.HasPrefix(key, "twitter:"):
+		if _, exists := meta.Twitter[key]; !exists {
+			meta.Twitter[key] = content
+		}
+	case strings.EqualFold(httpEquiv, "content-language") && meta.Language == "":
+		meta.Language = content
+	}
+}
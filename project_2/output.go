@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Куда выводятся сообщения о ходе работы. В машиночитаемых форматах - в stderr,
+// чтобы stdout можно было передать другой программе.
+var logOut io.Writer = os.Stdout
+
+// Функция для вывода сообщения о ходе работы
+func logf(format string, args ...interface{}) {
+	fmt.Fprintf(logOut, format, args...)
+}
+
+// Запись результата для машиночитаемых форматов
+type resultRecord struct {
+	URL        string              `json:"url"`
+	FinalURL   string              `json:"final_url,omitempty"`
+	StatusCode int                 `json:"status_code,omitempty"`
+	Title      string              `json:"title,omitempty"`
+	Charset    string              `json:"charset,omitempty"`
+	Content    string              `json:"content_type,omitempty"`
+	Status     string              `json:"status"`
+	ErrorClass string              `json:"error_class,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	ElapsedMs  float64             `json:"elapsed_ms"`
+	Bytes      int64               `json:"bytes"`
+	Truncated  string              `json:"truncated,omitempty"`
+	Cache      string              `json:"cache,omitempty"`
+	Words      int                 `json:"words,omitempty"`
+	TextLang   string              `json:"text_language,omitempty"`
+	Fields     map[string][]string `json:"fields,omitempty"`
+	ContentLen *int64              `json:"content_length,omitempty"`
+	Headers    map[string]string   `json:"headers,omitempty"`
+	Redirects  []redirectRecord    `json:"redirects,omitempty"`
+	Referrers  []string            `json:"referrers,omitempty"`
+}
+
+// Переход по редиректу в машиночитаемых форматах
+type redirectRecord struct {
+	StatusCode int    `json:"status_code"`
+	URL        string `json:"url"`
+}
+
+// Функция для преобразования результата в запись
+func toRecord(result ParseResult) resultRecord {
+	record := resultRecord{
+		URL:        result.URL,
+		FinalURL:   result.FinalURL,
+		StatusCode: result.StatusCode,
+		Title:      result.Title,
+		Charset:    result.Charset,
+		Content:    result.ContentType,
+		Status:     string(result.Status),
+		ErrorClass: string(result.ErrorClass),
+		ElapsedMs:  float64(result.Elapsed) / float64(time.Millisecond),
+		Bytes:      result.Bytes,
+		Truncated:  result.Truncated,
+		Cache:      result.Cache,
+		Words:      result.Words,
+		TextLang:   result.TextLanguage,
+		Fields:     result.Fields,
+		Headers:    result.Headers,
+		Referrers:  result.Referrers,
+	}
+	// Размер по Content-Length известен только для ответов из сети
+	if result.StatusCode != 0 && result.BodySize >= 0 {
+		size := result.BodySize
+		record.ContentLen = &size
+	}
+	for _, redirect := range result.Redirects {
+		record.Redirects = append(record.Redirects, redirectRecord{StatusCode: redirect.StatusCode, URL: redirect.URL})
+	}
+	if result.Error != nil {
+		record.Error = result.Error.Error()
+	}
+	return record
+}
+
+// Вывод результатов в одном из форматов
+type ResultWriter interface {
+	Write(result ParseResult) error // Вызывается для каждого результата по мере готовности
+	Close() error                   // Вызывается, когда результатов больше не будет
+}
+
+// Поддерживаемые форматы вывода
+var outputFormats = []string{"text", "json", "jsonl", "csv", "table"}
+
+// Создаем вывод в нужном формате ("text" - обычный вывод с эмодзи, writer не нужен)
+func newResultWriter(format string, w io.Writer) (ResultWriter, error) {
+	switch format {
+	case "json":
+		return &jsonWriter{w: w}, nil
+	case "jsonl":
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "table":
+		return &tableWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("неизвестный формат %q, доступны: %v", format, outputFormats)
+	}
+}
+
+// JSON Lines: по одному объекту на строку сразу после получения результата
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (jw *jsonlWriter) Write(result ParseResult) error {
+	return jw.enc.Encode(toRecord(result))
+}
+
+func (jw *jsonlWriter) Close() error {
+	return nil
+}
+
+// JSON: массив всех результатов в конце
+type jsonWriter struct {
+	w       io.Writer
+	records []resultRecord
+}
+
+func (jw *jsonWriter) Write(result ParseResult) error {
+	jw.records = append(jw.records, toRecord(result))
+	return nil
+}
+
+func (jw *jsonWriter) Close() error {
+	if jw.records == nil {
+		jw.records = []resultRecord{}
+	}
+	enc := json.NewEncoder(jw.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jw.records)
+}
+
+// CSV: заголовок и по строке на результат
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+var csvHeader = []string{"url", "final_url", "status_code", "title", "charset", "content_type", "status", "error_class", "error", "elapsed_ms", "bytes", "truncated", "cache", "words", "text_language", "fields", "content_length", "headers", "redirects", "referrers"}
+
+func (cw *csvWriter) Write(result ParseResult) error {
+	if !cw.headerWritten {
+		if err := cw.w.Write(csvHeader); err != nil {
+			return err
+		}
+		cw.headerWritten = true
+	}
+
+	record := toRecord(result)
+	contentLength := ""
+	if record.ContentLen != nil {
+		contentLength = strconv.FormatInt(*record.ContentLen, 10)
+	}
+	row := []string{
+		record.URL,
+		record.FinalURL,
+		strconv.Itoa(record.StatusCode),
+		record.Title,
+		record.Charset,
+		record.Content,
+		record.Status,
+		record.ErrorClass,
+		record.Error,
+		strconv.FormatFloat(record.ElapsedMs, 'f', 1, 64),
+		strconv.FormatInt(record.Bytes, 10),
+		record.Truncated,
+		record.Cache,
+		strconv.Itoa(record.Words),
+		record.TextLang,
+		formatFields(record.Fields),
+		contentLength,
+		formatHeaders(record.Headers),
+		formatRedirects(result.Redirects),
+		strings.Join(record.Referrers, " "),
+	}
+	if err := cw.w.Write(row); err != nil {
+		return err
+	}
+
+	// Сбрасываем буфер сразу, чтобы строки появлялись по мере готовности
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// Функция для записи полей в одну ячейку CSV: "name=значение; name2=значение1 | значение2"
+func formatFields(fields map[string][]string) string {
+	parts := make([]string, 0, len(fields))
+	for _, name := range fieldNames(fields) {
+		parts = append(parts, name+"="+fieldValue(fields[name]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (cw *csvWriter) Close() error {
+	if !cw.headerWritten {
+		if err := cw.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// Таблица с выровненными колонками для чтения человеком
+type tableWriter struct {
+	w       io.Writer
+	records []resultRecord
+}
+
+func (tw *tableWriter) Write(result ParseResult) error {
+	tw.records = append(tw.records, toRecord(result))
+	return nil
+}
+
+func (tw *tableWriter) Close() error {
+	table := tabwriter.NewWriter(tw.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "URL\tCODE\tSTATUS\tCLASS\tTIME\tBYTES\tTITLE")
+	for _, r := range tw.records {
+		code := "-"
+		if r.StatusCode != 0 {
+			code = strconv.Itoa(r.StatusCode)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%.0fms\t%d\t%s\n",
+			r.URL, code, r.Status, r.ErrorClass, r.ElapsedMs, r.Bytes, truncate(r.Title, 60))
+	}
+	return table.Flush()
+}
+
+// Функция для завершения вывода (например, закрывающая скобка JSON-массива)
+func closeResultWriter(writer ResultWriter) {
+	if err := writer.Close(); err != nil {
+		logf("❌ Ошибка вывода результатов: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// Функция для обрезки длинной строки
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Результаты для проверки форматов: успешный и с ошибкой
+func sampleResults() []ParseResult {
+	ok := ParseResult{URL: "https://example.com/", StatusCode: 200, Status: StatusOK, Elapsed: 1500 * time.Microsecond, Bytes: 42}
+	ok.Title = "Пример, с запятой и \"кавычками\""
+	failed := ParseResult{
+		URL:        "https://example.com/missing",
+		StatusCode: 503,
+		Status:     StatusError,
+		Error:      &HTTPStatusError{StatusCode: 503},
+		ErrorClass: ClassHTTP5xx,
+	}
+	return []ParseResult{ok, failed}
+}
+
+// Функция для вывода результатов в формате format
+func writeResults(t *testing.T, format string, results []ParseResult) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	writer, err := newResultWriter(format, &out)
+	if err != nil {
+		t.Fatalf("newResultWriter(%q): %v", format, err)
+	}
+	for _, result := range results {
+		if err := writer.Write(result); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return out.String()
+}
+
+// Проверяем записи, общие для всех машиночитаемых форматов
+func checkRecords(t *testing.T, records []resultRecord) {
+	t.Helper()
+
+	if len(records) != 2 {
+		t.Fatalf("записей %d, want 2", len(records))
+	}
+	first, second := records[0], records[1]
+	if first.URL != "https://example.com/" || first.Status != "ok" || first.Title != "Пример, с запятой и \"кавычками\"" ||
+		first.ElapsedMs != 1.5 || first.Bytes != 42 {
+		t.Errorf("первая запись: %+v", first)
+	}
+	if second.Status != "error" || second.ErrorClass != "http_5xx" || second.Error != "HTTP 503 Service Unavailable" {
+		t.Errorf("вторая запись: %+v", second)
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	var records []resultRecord
+	if err := json.Unmarshal([]byte(writeResults(t, "json", sampleResults())), &records); err != nil {
+		t.Fatalf("вывод не является JSON-массивом: %v", err)
+	}
+	checkRecords(t, records)
+
+	// Без результатов - пустой массив, а не null
+	if got := strings.TrimSpace(writeResults(t, "json", nil)); got != "[]" {
+		t.Errorf("пустой вывод: %q, want []", got)
+	}
+}
+
+func TestJSONLOutput(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(writeResults(t, "jsonl", sampleResults())), "\n")
+
+	var records []resultRecord
+	for _, line := range lines {
+		var record resultRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("строка %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	checkRecords(t, records)
+}
+
+func TestCSVOutput(t *testing.T) {
+	rows, err := csv.NewReader(strings.NewReader(writeResults(t, "csv", sampleResults()))).ReadAll()
+	if err != nil {
+		t.Fatalf("вывод не является CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("строк %d, want заголовок и 2 строки", len(rows))
+	}
+
+	column := make(map[string]int)
+	for i, name := range rows[0] {
+		column[name] = i
+	}
+	for _, name := range []string{"url", "title", "status", "error_class", "error", "elapsed_ms", "bytes"} {
+		if _, ok := column[name]; !ok {
+			t.Fatalf("нет колонки %q в заголовке %v", name, rows[0])
+		}
+	}
+	if got := rows[1][column["title"]]; got != "Пример, с запятой и \"кавычками\"" {
+		t.Errorf("title = %q", got)
+	}
+	if got := rows[1][column["elapsed_ms"]]; got != "1.5" {
+		t.Errorf("elapsed_ms = %q", got)
+	}
+	if got := rows[2][column["error_class"]]; got != "http_5xx" {
+		t.Errorf("error_class = %q", got)
+	}
+
+	// Заголовок пишется даже без результатов
+	if got := writeResults(t, "csv", nil); !strings.HasPrefix(got, "url,") {
+		t.Errorf("пустой CSV: %q", got)
+	}
+}
+
+func TestTableOutput(t *testing.T) {
+	lines := strings.Split(strings.TrimRight(writeResults(t, "table", sampleResults()), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("строк %d, want 3:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	if fields := strings.Fields(lines[0]); fields[0] != "URL" || fields[1] != "CODE" {
+		t.Errorf("заголовок: %q", lines[0])
+	}
+	// Колонки выровнены: STATUS в заголовке и в строках начинается с одной позиции
+	statusCol := strings.Index(lines[0], "STATUS")
+	if !strings.HasPrefix(lines[1][statusCol:], "ok") || !strings.HasPrefix(lines[2][statusCol:], "error") {
+		t.Errorf("колонки не выровнены:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestUnknownOutputFormat(t *testing.T) {
+	if _, err := newResultWriter("xml", &bytes.Buffer{}); err == nil {
+		t.Error("для неизвестного формата ожидалась ошибка")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("Привет, мир", 7); got != "Привет…" {
+		t.Errorf("truncate = %q", got)
+	}
+	if got := truncate("коротко", 60); got != "коротко" {
+		t.Errorf("truncate = %q", got)
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	neturl "net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Этапы конвейера по порядку
+var pipelineStages = []string{"fetch", "parse", "enrich", "sink"}
+
+// Настройки этапа: сколько воркеров и какая длина очереди на входе
+type StageConfig struct {
+	Workers int
+	Queue   int
+}
+
+// Настройки этапов по умолчанию: загрузка ждет сеть, поэтому воркеров много,
+// разбор и анализ текста нагружают процессор - воркеров по числу ядер,
+// вывод всегда один (писать результаты можно только по очереди)
+func defaultStageConfigs() map[string]StageConfig {
+	cpus := runtime.NumCPU()
+	return map[string]StageConfig{
+		"fetch":  {Workers: options.Workers, Queue: options.Workers},
+		"parse":  {Workers: cpus, Queue: 2 * cpus},
+		"enrich": {Workers: cpus, Queue: 2 * cpus},
+		"sink":   {Workers: 1, Queue: 16},
+	}
+}
+
+// Функция для разбора настроек этапов: "fetch=20:40,parse=4:8" (воркеры:очередь, любую часть можно опустить)
+func parseStageConfigs(spec string, configs map[string]StageConfig) error {
+	for _, item := range splitList(spec) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("ожидается этап=воркеры:очередь, получено %q", item)
+		}
+		name = strings.TrimSpace(name)
+		config, known := configs[name]
+		if !known {
+			return fmt.Errorf("неизвестный этап %q, доступны: %v", name, pipelineStages)
+		}
+
+		workers, queue, _ := strings.Cut(value, ":")
+		if workers = strings.TrimSpace(workers); workers != "" {
+			n, err := strconv.Atoi(workers)
+			if err != nil || n < 1 {
+				return fmt.Errorf("этап %s: количество воркеров должно быть не меньше 1", name)
+			}
+			config.Workers = n
+		}
+		if queue = strings.TrimSpace(queue); queue != "" {
+			n, err := strconv.Atoi(queue)
+			if err != nil || n < 0 {
+				return fmt.Errorf("этап %s: длина очереди не может быть отрицательной", name)
+			}
+			config.Queue = n
+		}
+		if name == "sink" && config.Workers != 1 {
+			return fmt.Errorf("этап sink: воркер может быть только один")
+		}
+
+		configs[name] = config
+	}
+	return nil
+}
+
+// Элемент, который проходит через этапы конвейера
+type pipelineItem struct {
+	index       int         // Позиция URL во входном списке
+	result      ParseResult // Заполняется по мере прохождения этапов
+	contentType string      // Content-Type ответа (для определения кодировки)
+	base        *neturl.URL // Адрес страницы после редиректов
+	body        []byte      // Тело страницы (нужно этапам parse и enrich)
+}
+
+// Статистика этапа конвейера
+type StageStats struct {
+	Name    string
+	Workers int
+	Queue   int // Длина очереди на входе этапа
+
+	mutex      sync.Mutex
+	processed  int
+	busy       time.Duration // Сколько времени воркеры этапа были заняты
+	lastDone   time.Duration // Когда этап обработал последний элемент (от начала конвейера)
+	depthSum   int           // Сумма длины входной очереди по всем замерам
+	depthMax   int           // Максимальная длина входной очереди
+	depthCount int           // Количество замеров
+}
+
+// Замер длины очереди, из которой воркер только что взял элемент
+func (s *StageStats) sampleQueue(depth int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.depthSum += depth
+	s.depthCount++
+	if depth > s.depthMax {
+		s.depthMax = depth
+	}
+}
+
+// Учитываем обработанный элемент
+func (s *StageStats) done(busy, sinceStart time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.processed++
+	s.busy += busy
+	if sinceStart > s.lastDone {
+		s.lastDone = sinceStart
+	}
+}
+
+// Функция для запуска этапа: workers горутин читают элементы из in (fan-out),
+// обрабатывают их и отправляют в общий выходной канал (fan-in) с очередью outQueue.
+// Выходной канал закрывается, когда in закрыт и все воркеры закончили.
+func runStage[T any](ctx context.Context, stats *StageStats, start time.Time, in <-chan T, outQueue int, work func(ctx context.Context, in T) *pipelineItem) <-chan *pipelineItem {
+	out := make(chan *pipelineItem, outQueue)
+
+	var wg sync.WaitGroup
+	for i := 0; i < stats.Workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for job := range in {
+				stats.sampleQueue(len(in))
+				jobStart := time.Now()
+				item := work(ctx, job)
+				stats.done(time.Since(jobStart), time.Since(start))
+
+				// Если следующий этап не успевает, воркер ждет здесь (backpressure)
+				out <- item
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// Этап fetch: robots.txt, загрузка страницы с повторами и кешем
+func fetchStage(ctx context.Context, item *pipelineItem) {
+	url := item.result.URL
+
+	if ctx.Err() != nil {
+		item.result = cancelledResult(url)
+		return
+	}
+	if robots != nil && !robots.Allowed(ctx, url) {
+		item.result = ParseResult{URL: url, Status: StatusBlocked}
+		return
+	}
+
+	item.result = withRetry(ctx, url, func() ParseResult {
+		return fetchBody(ctx, item)
+	})
+}
+
+// Функция для загрузки тела страницы целиком (не больше -max-body байт)
+func fetchBody(ctx context.Context, item *pipelineItem) ParseResult {
+	start := time.Now()
+	url := item.result.URL
+
+	opened, failed := openPage(ctx, url, start)
+	if failed != nil {
+		return *failed
+	}
+
+	if opened.resp == nil {
+		base, err := neturl.Parse(opened.cached.FinalURL)
+		if err != nil {
+			return errorResult(ctx, url, err, start)
+		}
+		item.contentType = opened.cached.ContentType
+		item.base = base
+		item.body = opened.cached.Body
+		result := ParseResult{
+			URL:     url,
+			Status:  StatusOK,
+			Elapsed: time.Since(start),
+			Cache:   opened.source,
+		}
+		applyCachedResponse(&result, opened.cached)
+		return result
+	}
+
+	resp := opened.resp
+	defer resp.Body.Close()
+
+	limited := &limitReader{r: resp.Body, left: options.MaxBody}
+	if options.MaxBody <= 0 {
+		limited.left = math.MaxInt64
+	}
+	body, err := io.ReadAll(limited)
+	if err != nil {
+		return errorResult(ctx, url, err, start)
+	}
+
+	truncated := TruncatedNone
+	if limited.hit {
+		truncated = TruncatedMaxBody
+	}
+	storePage(url, resp, body, truncated)
+
+	item.contentType = resp.Header.Get("Content-Type")
+	item.base = resp.Request.URL
+	item.body = body
+	result := ParseResult{
+		URL:       url,
+		Status:    StatusOK,
+		Elapsed:   time.Since(start),
+		Bytes:     int64(len(body)),
+		Truncated: truncated,
+		Cache:     opened.source,
+	}
+	applyResponse(&result, resp)
+	return result
+}
+
+// Этап parse: кодировка, метаданные и поля по правилам из загруженного тела
+func parseStage(ctx context.Context, item *pipelineItem) {
+	if item.result.Status != StatusOK {
+		return
+	}
+
+	page, err := readPage(bytes.NewReader(item.body), item.contentType, int64(len(item.body)), item.base, false)
+	if err != nil {
+		item.result.Status = StatusError
+		item.result.Error = err
+		item.result.ErrorClass = ClassOther
+		return
+	}
+	item.result.PageMeta = page.meta
+	item.result.Fields = extractFields(extractRules.For(item.result.URL), item.result.URL, item.contentType, item.body, page.meta.Charset)
+}
+
+// Этап enrich: количество слов и язык текста (только для HTML и обычного текста)
+func enrichStage(ctx context.Context, item *pipelineItem) {
+	if item.result.Status != StatusOK || (item.result.ContentType != ContentHTML && item.result.ContentType != ContentText) {
+		return
+	}
+
+	stats, err := analyzeText(newCharsetReader(bytes.NewReader(item.body), item.result.Charset))
+	if err != nil {
+		return
+	}
+	item.result.Words = stats.Words
+	item.result.TextLanguage = stats.Language
+}
+
+// Параллельный парсинг конвейером: fetch -> parse -> enrich -> sink.
+// У каждого этапа свои воркеры и своя очередь, поэтому медленный этап можно
+// масштабировать отдельно. Возвращает результаты и реальное время работы.
+func parsePipeline(ctx context.Context, urls []string, configs map[string]StageConfig) ([]ParseResult, time.Duration) {
+	logf("🏭 Запуск конвейера:")
+	for _, name := range pipelineStages {
+		logf(" %s %d:%d", name, configs[name].Workers, configs[name].Queue)
+	}
+	logf(" (воркеры:очередь)\n")
+	start := time.Now()
+	p := startProgress("Конвейер", len(urls), len(urls))
+
+	stats := make(map[string]*StageStats, len(pipelineStages))
+	for _, name := range pipelineStages {
+		stats[name] = &StageStats{Name: name, Workers: configs[name].Workers, Queue: configs[name].Queue}
+	}
+
+	// Источник: планировщик выдает URL с учетом ограничений по хостам
+	sched := NewHostScheduler(urls, options.PerHost, hostDelay)
+	jobs := make(chan poolJob, configs["fetch"].Queue)
+	go sched.Feed(ctx, jobs)
+
+	fetched := runStage(ctx, stats["fetch"], start, jobs, configs["parse"].Queue, func(ctx context.Context, job poolJob) *pipelineItem {
+		if !p.Active() {
+			logf("   Загрузка: %s\n", job.url)
+		}
+		sched.Start(ctx, job.url)
+		p.Begin(job.url)
+		item := &pipelineItem{index: job.index, result: ParseResult{URL: job.url}}
+		fetchStage(withHostLimits(ctx, sched.limits), item)
+		sched.Release(job.url)
+		return item
+	})
+	parsed := runStage(ctx, stats["parse"], start, fetched, configs["enrich"].Queue, func(ctx context.Context, item *pipelineItem) *pipelineItem {
+		parseStage(ctx, item)
+		return item
+	})
+	enriched := runStage(ctx, stats["enrich"], start, parsed, configs["sink"].Queue, func(ctx context.Context, item *pipelineItem) *pipelineItem {
+		enrichStage(ctx, item)
+		return item
+	})
+
+	// Sink: один получатель собирает результаты (в порядке входного списка или по готовности)
+	sink := stats["sink"]
+	collector := newResultCollector(len(urls), options.Unordered)
+	for item := range enriched {
+		sink.sampleQueue(len(enriched))
+		itemStart := time.Now()
+		item.body = nil
+		p.Finish(item.result)
+		collector.add(indexedResult{index: item.index, result: item.result})
+		sink.done(time.Since(itemStart), time.Since(start))
+	}
+
+	// Канал задач закрыт, значит Feed завершился - забираем то, что не успели выдать
+	for _, job := range sched.Remaining() {
+		collector.add(indexedResult{index: job.index, result: cancelledResult(job.url)})
+	}
+
+	p.Stop()
+
+	elapsed := time.Since(start)
+	logf("✅ Конвейер завершен за: %v\n", elapsed)
+	printStageStats(stats, elapsed)
+
+	return collector.results(), elapsed
+}
+
+// Функция для вывода статистики этапов конвейера
+func printStageStats(stats map[string]*StageStats, wall time.Duration) {
+	for _, name := range pipelineStages {
+		s := stats[name]
+		s.mutex.Lock()
+
+		throughput, utilization, avgDepth := 0.0, 0.0, 0.0
+		if s.lastDone > 0 {
+			throughput = float64(s.processed) / s.lastDone.Seconds()
+		}
+		if wall > 0 {
+			utilization = float64(s.busy) / (float64(wall) * float64(s.Workers))
+		}
+		if s.depthCount > 0 {
+			avgDepth = float64(s.depthSum) / float64(s.depthCount)
+		}
+
+		logf("   %-6s воркеров %2d, очередь %3d: обработано %d (%.1f/с), загрузка %3.0f%%, в очереди в среднем %.1f, максимум %d\n",
+			s.Name, s.Workers, s.Queue, s.processed, throughput, utilization*100, avgDepth, s.depthMax)
+
+		s.mutex.Unlock()
+	}
+	logf("\n")
+}
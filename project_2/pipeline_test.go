@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePipeline(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.Workers = 3
+	options.Unordered = false
+
+	server := newTestFixture(t)
+	var urls []string
+	for i := 1; i <= 6; i++ {
+		urls = append(urls, fmt.Sprintf("%s/page/%d", server.URL, i))
+	}
+	urls = append(urls, server.URL+"/page/7?status=404", server.URL+"/page/8?hops=2")
+
+	configs := defaultStageConfigs()
+	if err := parseStageConfigs("parse=2:1,enrich=1:0", configs); err != nil {
+		t.Fatal(err)
+	}
+	results, _ := parsePipeline(context.Background(), urls, configs)
+
+	if len(results) != len(urls) {
+		t.Fatalf("результатов %d, want %d", len(results), len(urls))
+	}
+	for i, result := range results {
+		// Результаты в порядке входного списка, даже если этапы закончили их в другом порядке
+		if result.URL != urls[i] {
+			t.Fatalf("результат %d: URL %s, want %s", i, result.URL, urls[i])
+		}
+		if i == 6 {
+			if result.Status != StatusError || result.StatusCode != 404 {
+				t.Errorf("%s: статус %s, код %d, want error 404", result.URL, result.Status, result.StatusCode)
+			}
+			continue
+		}
+
+		n := i + 1
+		if result.Status != StatusOK {
+			t.Errorf("%s: статус %s: %v", result.URL, result.Status, result.Error)
+			continue
+		}
+		// Этап parse заполнил метаданные, этап enrich - статистику текста
+		if !strings.Contains(result.Title, fmt.Sprintf("Тестовая страница %d", n)) {
+			t.Errorf("%s: Title = %q", result.URL, result.Title)
+		}
+		if result.Words == 0 || result.TextLanguage == "" {
+			t.Errorf("%s: слов %d, язык %q", result.URL, result.Words, result.TextLanguage)
+		}
+	}
+	if final := results[7].FinalURL; final == urls[7] || !strings.Contains(final, "/page/8") {
+		t.Errorf("после редиректов FinalURL = %s, want адрес последнего перехода", final)
+	}
+}
+
+func TestParseStageConfigs(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.Workers = 10
+
+	configs := defaultStageConfigs()
+	if err := parseStageConfigs("fetch=20, parse=:8", configs); err != nil {
+		t.Fatal(err)
+	}
+	if got := configs["fetch"]; got.Workers != 20 || got.Queue != 10 {
+		t.Errorf("fetch = %+v, want 20 воркеров и очередь 10", got)
+	}
+	if got := configs["parse"]; got.Queue != 8 {
+		t.Errorf("parse = %+v, want очередь 8", got)
+	}
+
+	for _, bad := range []string{"fetch", "store=2", "fetch=0", "parse=2:-1", "sink=2"} {
+		if err := parseStageConfigs(bad, defaultStageConfigs()); err == nil {
+			t.Errorf("parseStageConfigs(%q): ожидалась ошибка", bad)
+		}
+	}
+}
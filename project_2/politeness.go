@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Задача для пула: URL и его позиция во входном списке
+type poolJob struct {
+	index int
+	url   string
+}
+
+// Очередь одного хоста в планировщике
+type hostState struct {
+	queue []poolJob // Задачи этого хоста, ожидающие отправки
+}
+
+// Ограничения одного хоста
+type hostLimit struct {
+	active      int       // Сколько запросов к хосту выполняется сейчас
+	nextAllowed time.Time // Раньше этого времени хост трогать нельзя
+	lastStart   time.Time // Когда начался (или начнется) последний запрос к хосту
+}
+
+// Ограничения по хостам: сколько запросов к хосту выполняется и когда к нему можно снова.
+// Их можно разделить между несколькими планировщиками (задания сервиса -serve):
+// тогда -per-host и -host-delay действуют на все их URL вместе.
+type HostLimits struct {
+	perHost  int                               // Максимум одновременных запросов к хосту (0 - без ограничения)
+	delayFor func(rawURL string) time.Duration // Минимальная пауза между запросами к хосту
+
+	mutex sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+// Создаем ограничения по хостам
+func NewHostLimits(perHost int, delayFor func(rawURL string) time.Duration) *HostLimits {
+	return &HostLimits{
+		perHost:  perHost,
+		delayFor: delayFor,
+		hosts:    make(map[string]*hostLimit),
+	}
+}
+
+// Ограничения хоста, запись создается при первом обращении (вызывать под мьютексом)
+func (l *HostLimits) limitLocked(host string) *hostLimit {
+	limit, exists := l.hosts[host]
+	if !exists {
+		limit = &hostLimit{}
+		l.hosts[host] = limit
+	}
+	return limit
+}
+
+// Запрос к хосту начинается: выдерживаем паузу от начала предыдущего запроса к нему.
+// Планировщик отсчитывает паузу при выдаче URL, но выданный URL может подождать
+// в очереди воркеров, и тогда два запроса к хосту ушли бы подряд. Поэтому воркер
+// вызывает Start прямо перед запросом: время начала резервируется под мьютексом,
+// и каждый следующий запрос к хосту начнется не раньше чем через паузу.
+func (l *HostLimits) Start(ctx context.Context, rawURL string) {
+	// Crawl-delay известен только после загрузки robots.txt: ждем ее, пока пауза не посчитана
+	// (загрузка одна на сайт, остальные воркеры этого хоста ждут ту же)
+	if robots != nil {
+		robots.Prefetch(ctx, rawURL)
+	}
+
+	l.mutex.Lock()
+	start := time.Now()
+	delay := l.delayFor(rawURL)
+	limit := l.limitLocked(hostKey(rawURL))
+	if earliest := limit.lastStart.Add(delay); earliest.After(start) {
+		start = earliest
+	}
+	limit.lastStart = start
+	if next := start.Add(delay); next.After(limit.nextAllowed) {
+		limit.nextAllowed = next
+	}
+	l.mutex.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+	}
+}
+
+// Отмечаем, что запрос к хосту завершился. Запись свободного хоста, пауза которого
+// уже прошла, удаляем: у долгоживущего сервиса иначе копились бы все хосты.
+func (l *HostLimits) Release(rawURL string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	host := hostKey(rawURL)
+	if limit, exists := l.hosts[host]; exists {
+		limit.active--
+		if limit.active <= 0 && !time.Now().Before(limit.nextAllowed) {
+			delete(l.hosts, host)
+		}
+	}
+}
+
+// Планировщик "вежливого" обхода: ограничивает число одновременных запросов
+// к одному хосту и выдерживает паузу между обращениями к нему.
+// URL разных хостов выдаются по кругу, поэтому медленный хост не задерживает остальные.
+type HostScheduler struct {
+	limits *HostLimits // Ограничения хостов (свои или общие с другими планировщиками)
+
+	mutex   sync.Mutex
+	hosts   map[string]*hostState
+	order   []string      // Хосты в порядке обхода по кругу
+	cursor  int           // С какого хоста начинать следующий поиск
+	pending int           // Сколько URL еще не отправлено
+	wake    chan struct{} // Сигнал "хост освободился"
+}
+
+// Функция для получения ключа хоста из URL
+func hostKey(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// Создаем планировщик со своими ограничениями и раскладываем URL по очередям хостов
+func NewHostScheduler(urls []string, perHost int, delayFor func(rawURL string) time.Duration) *HostScheduler {
+	return NewSharedHostScheduler(urls, NewHostLimits(perHost, delayFor))
+}
+
+// Создаем планировщик, который делит ограничения хостов limits с другими планировщиками
+func NewSharedHostScheduler(urls []string, limits *HostLimits) *HostScheduler {
+	s := &HostScheduler{
+		limits: limits,
+		hosts:  make(map[string]*hostState),
+		wake:   make(chan struct{}, 1),
+	}
+
+	for i, u := range urls {
+		s.add(poolJob{index: i, url: u})
+	}
+
+	return s
+}
+
+// Добавляем задачу в очередь ее хоста
+func (s *HostScheduler) add(job poolJob) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	host := hostKey(job.url)
+	state, exists := s.hosts[host]
+	if !exists {
+		state = &hostState{}
+		s.hosts[host] = state
+		s.order = append(s.order, host)
+	}
+	state.queue = append(state.queue, job)
+	s.pending++
+}
+
+// Ищем следующую задачу, которую можно отправить прямо сейчас.
+// Если такой нет, возвращаем время, когда стоит проверить снова (нулевое - ждать освобождения хоста).
+func (s *HostScheduler) next(now time.Time) (poolJob, time.Time, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.limits.mutex.Lock()
+	defer s.limits.mutex.Unlock()
+
+	var wakeAt time.Time
+	for i := 0; i < len(s.order); i++ {
+		idx := (s.cursor + i) % len(s.order)
+		host := s.order[idx]
+		state := s.hosts[host]
+
+		if len(state.queue) == 0 {
+			continue
+		}
+		limit := s.limits.limitLocked(host)
+		if s.limits.perHost > 0 && limit.active >= s.limits.perHost {
+			continue
+		}
+		if now.Before(limit.nextAllowed) {
+			if wakeAt.IsZero() || limit.nextAllowed.Before(wakeAt) {
+				wakeAt = limit.nextAllowed
+			}
+			continue
+		}
+
+		job := state.queue[0]
+		state.queue = state.queue[1:]
+		limit.active++
+		limit.nextAllowed = now.Add(s.limits.delayFor(job.url))
+		s.pending--
+
+		// Следующий поиск начинаем со следующего хоста - так хосты чередуются
+		s.cursor = (idx + 1) % len(s.order)
+		return job, time.Time{}, true
+	}
+
+	return poolJob{}, wakeAt, false
+}
+
+// Запрос к хосту начинается (см. HostLimits.Start)
+func (s *HostScheduler) Start(ctx context.Context, rawURL string) {
+	s.limits.Start(ctx, rawURL)
+}
+
+// Отмечаем, что запрос к хосту завершился
+func (s *HostScheduler) Release(rawURL string) {
+	s.limits.Release(rawURL)
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Остались ли неотправленные URL
+func (s *HostScheduler) hasPending() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.pending > 0
+}
+
+// Возвращаем задачу в начало очереди ее хоста (если ее не удалось отправить)
+func (s *HostScheduler) requeue(job poolJob) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state := s.hosts[hostKey(job.url)]
+	state.queue = append([]poolJob{job}, state.queue...)
+	s.pending++
+	s.limits.Release(job.url)
+}
+
+// Неотправленные задачи (после отмены)
+func (s *HostScheduler) Remaining() []poolJob {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var remaining []poolJob
+	for _, host := range s.order {
+		remaining = append(remaining, s.hosts[host].queue...)
+	}
+	return remaining
+}
+
+// Отправляем URL в канал задач с учетом ограничений хостов, затем закрываем канал.
+// При отмене ctx отправка прекращается, невыданные URL остаются в очередях.
+func (s *HostScheduler) Feed(ctx context.Context, jobs chan<- poolJob) {
+	defer close(jobs)
+
+	for s.hasPending() {
+		job, wakeAt, ok := s.next(time.Now())
+		if ok {
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				s.requeue(job)
+				return
+			}
+			continue
+		}
+
+		// Ждем, пока освободится хост или истечет пауза
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !wakeAt.IsZero() {
+			timer = time.NewTimer(time.Until(wakeAt))
+			timeout = timer.C
+		}
+
+		select {
+		case <-s.wake:
+		case <-timeout:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Функция для паузы перед запросом в последовательном режиме:
+// выдерживает delay с момента предыдущего обращения к тому же хосту
+func waitForHost(ctx context.Context, lastHit map[string]time.Time, rawURL string, delay time.Duration) {
+	host := hostKey(rawURL)
+	if last, ok := lastHit[host]; ok {
+		if wait := delay - time.Since(last); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+			}
+			timer.Stop()
+		}
+	}
+	lastHit[host] = time.Now()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Время начала запросов по хостам
+type startLog struct {
+	mutex  sync.Mutex
+	starts map[string][]time.Time
+}
+
+func (l *startLog) record(rawURL string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.starts == nil {
+		l.starts = make(map[string][]time.Time)
+	}
+	l.starts[hostKey(rawURL)] = append(l.starts[hostKey(rawURL)], time.Now())
+}
+
+// Наименьшая пауза между соседними запросами к хосту
+func (l *startLog) minGap(host string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	starts := l.starts[host]
+	gap := time.Duration(-1)
+	for i := 1; i < len(starts); i++ {
+		if d := starts[i].Sub(starts[i-1]); gap < 0 || d < gap {
+			gap = d
+		}
+	}
+	return gap
+}
+
+// Воркеры заняты медленным хостом, а URL быстрого хоста тем временем ждут в очереди
+// пула. Когда воркеры освобождаются, эти URL все равно должны уйти с паузой.
+func TestHostDelayFromActualStart(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	urls := []string{"http://slow/1", "http://slow/2", "http://fast/1", "http://fast/2", "http://fast/3"}
+	sched := NewHostScheduler(urls, 2, func(string) time.Duration { return delay })
+
+	var log startLog
+	results, _ := runWorkerPool(context.Background(), urls, 2, sched, func(ctx context.Context, u string) ParseResult {
+		log.record(u)
+		if hostKey(u) == "slow" {
+			time.Sleep(3 * delay)
+		}
+		return ParseResult{URL: u, Status: StatusOK}
+	})
+
+	if len(results) != len(urls) {
+		t.Fatalf("результатов %d, want %d", len(results), len(urls))
+	}
+	for _, host := range []string{"slow", "fast"} {
+		// Таймер может сработать чуть раньше - допускаем погрешность
+		if gap := log.minGap(host); gap < delay-5*time.Millisecond {
+			t.Errorf("пауза между запросами к %s: %v, want >= %v", host, gap, delay)
+		}
+	}
+}
+
+func TestHostSchedulerPerHostLimit(t *testing.T) {
+	urls := []string{"http://a/1", "http://a/2", "http://a/3", "http://a/4", "http://b/1", "http://b/2"}
+	sched := NewHostScheduler(urls, 2, func(string) time.Duration { return 0 })
+
+	var mutex sync.Mutex
+	active, maxActive := map[string]int{}, map[string]int{}
+	runWorkerPool(context.Background(), urls, 6, sched, func(ctx context.Context, u string) ParseResult {
+		host := hostKey(u)
+		mutex.Lock()
+		active[host]++
+		if active[host] > maxActive[host] {
+			maxActive[host] = active[host]
+		}
+		mutex.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mutex.Lock()
+		active[host]--
+		mutex.Unlock()
+		return ParseResult{URL: u, Status: StatusOK}
+	})
+
+	for host, n := range maxActive {
+		if n > 2 {
+			t.Errorf("одновременно к %s: %d запросов, want <= 2", host, n)
+		}
+	}
+}
+
+// Два планировщика с общими ограничениями (задания сервиса) вместе не превышают -per-host
+func TestSharedHostLimits(t *testing.T) {
+	limits := NewHostLimits(1, func(string) time.Duration { return 0 })
+	first := NewSharedHostScheduler([]string{"http://a/1"}, limits)
+	second := NewSharedHostScheduler([]string{"http://a/2"}, limits)
+
+	now := time.Now()
+	if _, _, ok := first.next(now); !ok {
+		t.Fatal("первый планировщик должен выдать URL")
+	}
+	if _, _, ok := second.next(now); ok {
+		t.Fatal("второй планировщик не должен выдать URL того же хоста сверх -per-host")
+	}
+
+	first.Release("http://a/1")
+	if job, _, ok := second.next(now); !ok || job.url != "http://a/2" {
+		t.Fatalf("после освобождения хоста ожидался http://a/2, получено %q (%v)", job.url, ok)
+	}
+}
+
+func TestSharedHostLimitsDelay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	limits := NewHostLimits(0, func(string) time.Duration { return delay })
+	first := NewSharedHostScheduler([]string{"http://a/1"}, limits)
+	second := NewSharedHostScheduler([]string{"http://a/2"}, limits)
+
+	now := time.Now()
+	if _, _, ok := first.next(now); !ok {
+		t.Fatal("первый планировщик должен выдать URL")
+	}
+	_, wakeAt, ok := second.next(now)
+	if ok {
+		t.Fatal("второй планировщик не должен выдать URL того же хоста раньше паузы")
+	}
+	if wakeAt.Sub(now) != delay {
+		t.Errorf("проверить снова через %v, want %v", wakeAt.Sub(now), delay)
+	}
+}
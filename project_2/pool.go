@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Статистика работы пула воркеров
+type PoolStats struct {
+	Workers   int             // Количество воркеров
+	Jobs      []int           // Сколько задач выполнил каждый воркер
+	Busy      []time.Duration // Сколько времени каждый воркер был занят
+	WallClock time.Duration   // Общее время работы пула
+}
+
+// Загрузка воркеров: доля времени, которое воркеры были заняты работой
+func (s PoolStats) Utilization() float64 {
+	if s.Workers == 0 || s.WallClock <= 0 {
+		return 0
+	}
+
+	var busy time.Duration
+	for _, b := range s.Busy {
+		busy += b
+	}
+	return float64(busy) / (float64(s.WallClock) * float64(s.Workers))
+}
+
+// Функция для вывода статистики пула
+func printPoolStats(stats PoolStats) {
+	minJobs, maxJobs := 0, 0
+	for i, n := range stats.Jobs {
+		if i == 0 || n < minJobs {
+			minJobs = n
+		}
+		if n > maxJobs {
+			maxJobs = n
+		}
+	}
+
+	logf("👷 Воркеров: %d, загрузка: %.0f%%, задач на воркер: от %d до %d\n\n",
+		stats.Workers, stats.Utilization()*100, minJobs, maxJobs)
+}
+
+// Функция для запуска пула воркеров.
+// Очередь задач ограничена: если воркеры не успевают, отправитель ждет (backpressure),
+// поэтому даже для 100 000 URL одновременно работает не больше workers горутин и соединений.
+// Порядок выдачи URL и ограничения по хостам определяет планировщик sched.
+// При отмене ctx новые URL не выдаются, а невыданные попадают в результаты как отмененные.
+// Результаты возвращаются в порядке входного списка (с -unordered - в порядке готовности).
+func runWorkerPool(ctx context.Context, urls []string, workers int, sched *HostScheduler, work func(ctx context.Context, url string) ParseResult) ([]ParseResult, PoolStats) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(urls) && len(urls) > 0 {
+		workers = len(urls)
+	}
+
+	start := time.Now()
+	stats := PoolStats{
+		Workers: workers,
+		Jobs:    make([]int, workers),
+		Busy:    make([]time.Duration, workers),
+	}
+
+	// Ограниченные каналы задач и результатов
+	jobs := make(chan poolJob, workers)
+	resultsChan := make(chan indexedResult, workers)
+
+	// Повторные попытки внутри work тоже идут через ограничения хостов планировщика
+	workCtx := withHostLimits(ctx, sched.limits)
+
+	var wg sync.WaitGroup
+	for id := 0; id < workers; id++ {
+		wg.Add(1)
+
+		go func(id int) {
+			defer wg.Done()
+
+			// Каждый воркер пишет только в свою ячейку статистики - гонки нет
+			for job := range jobs {
+				sched.Start(ctx, job.url)
+				jobStart := time.Now()
+				progress.Begin(job.url)
+				result := work(workCtx, job.url)
+				progress.Finish(result)
+				stats.Busy[id] += time.Since(jobStart)
+				stats.Jobs[id]++
+				sched.Release(job.url)
+
+				resultsChan <- indexedResult{index: job.index, result: result}
+			}
+		}(id)
+	}
+
+	// Отправляем задачи: блокируется, когда очередь заполнена
+	go sched.Feed(ctx, jobs)
+
+	go func() {
+		wg.Wait()
+		close(resultsChan)
+	}()
+
+	collector := newResultCollector(len(urls), options.Unordered)
+	for item := range resultsChan {
+		collector.add(item)
+	}
+
+	// Канал задач закрыт, значит Feed завершился - забираем то, что не успели выдать
+	for _, job := range sched.Remaining() {
+		collector.add(indexedResult{index: job.index, result: cancelledResult(job.url)})
+	}
+	results := collector.results()
+
+	stats.WallClock = time.Since(start)
+	return results, stats
+}
+
+// Результат вместе с позицией URL во входном списке
+type indexedResult struct {
+	index  int
+	result ParseResult
+}
+
+// Сборщик результатов: раскладывает их по позициям входного списка
+// и передает в onResult либо сразу (unordered), либо строго по порядку,
+// как только готов очередной результат без "дырок" перед ним
+type resultCollector struct {
+	unordered bool
+	ordered   []ParseResult // Результаты по позициям
+	ready     []bool        // Готов ли результат на позиции
+	next      int           // Первая позиция, которую еще не передали в onResult
+	arrival   []ParseResult // Результаты в порядке готовности
+}
+
+// Создаем сборщик на n результатов
+func newResultCollector(n int, unordered bool) *resultCollector {
+	return &resultCollector{
+		unordered: unordered,
+		ordered:   make([]ParseResult, n),
+		ready:     make([]bool, n),
+	}
+}
+
+// Добавляем результат. Дубликат страницы при обходе (см. crawlState.complete)
+// в поток не передается.
+func (c *resultCollector) add(item indexedResult) {
+	c.ordered[item.index] = item.result
+	c.ready[item.index] = true
+	c.arrival = append(c.arrival, item.result)
+
+	if c.unordered {
+		if onResult != nil && !item.result.duplicate {
+			onResult(item.result)
+		}
+		return
+	}
+
+	// Отдаем все результаты, которые уже идут подряд
+	for c.next < len(c.ready) && c.ready[c.next] {
+		if onResult != nil && !c.ordered[c.next].duplicate {
+			onResult(c.ordered[c.next])
+		}
+		c.next++
+	}
+}
+
+// Итоговый список результатов
+func (c *resultCollector) results() []ParseResult {
+	if c.unordered {
+		return c.arrival
+	}
+	return c.ordered
+}
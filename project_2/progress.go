@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Режимы отображения хода работы
+var progressModes = []string{"auto", "tty", "plain", "off"}
+
+// Текущий режим (-progress)
+var progressMode = "auto"
+
+// В режиме auto ход работы показывается, только если URL хотя бы столько
+const progressMinURLs = 50
+
+// Как часто перерисовывать живой прогресс и как часто писать строку в режиме plain
+const (
+	progressTTYInterval   = 200 * time.Millisecond
+	progressPlainInterval = 5 * time.Second
+)
+
+// Сколько самых долгих запросов показывать
+const progressSlowest = 3
+
+// Прогресс текущего запуска (nil - не показывается)
+var progress *Progress
+
+// Ход работы: сколько URL обработано, с каким итогом и какие запросы идут дольше всех
+type Progress struct {
+	title string
+	live  bool // Перерисовывать на месте (терминал) или писать строки (plain)
+	out   io.Writer
+	start time.Time
+
+	mutex    sync.Mutex
+	total    int
+	ok       int
+	failed   int
+	skipped  int                  // Запрещены robots.txt или отменены
+	inFlight map[string]time.Time // Запросы, которые выполняются прямо сейчас, и время их начала
+	lines    int                  // Сколько строк нарисовано в прошлый раз (для перерисовки)
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Функция для проверки, что вывод идет в терминал (а не в файл или конвейер)
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Функция для запуска отображения хода работы. expected - сколько URL ожидается
+// (от него зависит, включать ли прогресс в режиме auto). Возвращает nil, если прогресс не нужен.
+func startProgress(title string, total, expected int) *Progress {
+	var live bool
+	switch progressMode {
+	case "off":
+		return nil
+	case "tty":
+		live = true
+	case "plain":
+		live = false
+	default:
+		if expected < progressMinURLs {
+			return nil
+		}
+		live = isTerminal(logOut)
+	}
+
+	p := &Progress{
+		title:    title,
+		live:     live,
+		out:      logOut,
+		start:    time.Now(),
+		total:    total,
+		inFlight: make(map[string]time.Time),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	progress = p
+
+	go p.loop()
+	return p
+}
+
+// Увеличиваем ожидаемое количество URL (при обходе сайта оно растет по уровням)
+func (p *Progress) AddTotal(n int) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.total += n
+}
+
+// Запрос к URL начался
+func (p *Progress) Begin(url string) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.inFlight[url] = time.Now()
+}
+
+// URL обработан
+func (p *Progress) Finish(result ParseResult) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.inFlight, result.URL)
+	switch result.Status {
+	case StatusOK:
+		p.ok++
+	case StatusError:
+		p.failed++
+	default:
+		p.skipped++
+	}
+}
+
+// Показывается ли прогресс (тогда строки "Парсинг: ..." для каждого URL не нужны)
+func (p *Progress) Active() bool {
+	return p != nil
+}
+
+// Останавливаем отображение: последний раз перерисовываем итог
+func (p *Progress) Stop() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	progress = nil
+}
+
+// Цикл отображения: перерисовка по таймеру до остановки
+func (p *Progress) loop() {
+	defer close(p.done)
+
+	interval := progressPlainInterval
+	if p.live {
+		interval = progressTTYInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.render()
+		case <-p.stop:
+			if p.live {
+				p.render()
+			}
+			return
+		}
+	}
+}
+
+// Долгий запрос: URL и сколько он уже идет
+type slowRequest struct {
+	url     string
+	elapsed time.Duration
+}
+
+// Функция для отрисовки текущего состояния
+func (p *Progress) render() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(p.start)
+	completed := p.ok + p.failed + p.skipped
+
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(completed) / elapsed.Seconds()
+	}
+	eta := "?"
+	if rate > 0 && p.total >= completed {
+		eta = time.Duration(float64(p.total-completed) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+	percent := 0.0
+	if p.total > 0 {
+		percent = float64(completed) / float64(p.total) * 100
+	}
+
+	slowest := make([]slowRequest, 0, len(p.inFlight))
+	for url, started := range p.inFlight {
+		slowest = append(slowest, slowRequest{url: url, elapsed: now.Sub(started)})
+	}
+	sort.Slice(slowest, func(i, j int) bool { return slowest[i].elapsed > slowest[j].elapsed })
+	if len(slowest) > progressSlowest {
+		slowest = slowest[:progressSlowest]
+	}
+
+	status := fmt.Sprintf("%d/%d (%.0f%%) ✅ %d ❌ %d", completed, p.total, percent, p.ok, p.failed)
+	if p.skipped > 0 {
+		status += fmt.Sprintf(" ⏭️  %d", p.skipped)
+	}
+	status += fmt.Sprintf(" | %.1f запр/с | осталось ~%s", rate, eta)
+
+	if !p.live {
+		line := fmt.Sprintf("⏳ %s: %s", p.title, status)
+		if len(slowest) > 0 {
+			line += fmt.Sprintf(" | самый долгий: %s (%v)", slowest[0].url, slowest[0].elapsed.Round(100*time.Millisecond))
+		}
+		fmt.Fprintln(p.out, line)
+		return
+	}
+
+	lines := []string{fmt.Sprintf("⏳ %s %s %s", p.title, progressBar(percent, 20), status)}
+	for _, slow := range slowest {
+		lines = append(lines, fmt.Sprintf("   🐢 %6v %s", slow.elapsed.Round(100*time.Millisecond), truncate(slow.url, 100)))
+	}
+
+	// Возвращаемся к началу прошлой отрисовки и стираем ее
+	var b strings.Builder
+	if p.lines > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", p.lines)
+	}
+	b.WriteString("\r\x1b[J")
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	io.WriteString(p.out, b.String())
+	p.lines = len(lines)
+}
+
+// Функция для рисования полосы прогресса
+func progressBar(percent float64, width int) string {
+	filled := int(percent / 100 * float64(width))
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Заголовки ответа, которые сохраняются в результате (-headers)
+var captureHeaders = []string{"Content-Type", "Server", "Cache-Control", "Last-Modified"}
+
+// Функция для разбора списка заголовков: имена приводятся к каноническому виду (content-type -> Content-Type)
+func parseHeaderList(spec string) []string {
+	var names []string
+	for _, name := range splitList(spec) {
+		names = append(names, http.CanonicalHeaderKey(name))
+	}
+	return names
+}
+
+// Функция для восстановления цепочки редиректов по ответу: Go-клиент проходит
+// редиректы сам, но у каждого следующего запроса сохраняется ответ, который к нему привел
+func responseRedirects(resp *http.Response) []Redirect {
+	var redirects []Redirect
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		redirects = append(redirects, Redirect{StatusCode: req.Response.StatusCode, URL: req.URL.String()})
+	}
+
+	// Шли от последнего перехода к первому - разворачиваем
+	for i, j := 0, len(redirects)-1; i < j; i, j = i+1, j-1 {
+		redirects[i], redirects[j] = redirects[j], redirects[i]
+	}
+	return redirects
+}
+
+// Функция для выбора интересных заголовков ответа (nil - ни одного нет)
+func responseHeaders(header http.Header) map[string]string {
+	var headers map[string]string
+	for _, name := range captureHeaders {
+		if value := header.Get(name); value != "" {
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+			headers[name] = value
+		}
+	}
+	return headers
+}
+
+// Функция для заполнения сведений об ответе: код, адрес после редиректов,
+// цепочка редиректов, заголовки и размер тела по Content-Length
+func applyResponse(result *ParseResult, resp *http.Response) {
+	result.StatusCode = resp.StatusCode
+	result.FinalURL = resp.Request.URL.String()
+	result.Redirects = responseRedirects(resp)
+	result.Headers = responseHeaders(resp.Header)
+	result.BodySize = resp.ContentLength
+}
+
+// Функция для заполнения сведений об ответе из записи кеша: цепочка редиректов
+// в кеше не хранится, а из заголовков известны только сохраненные
+func applyCachedResponse(result *ParseResult, entry *cacheEntry) {
+	result.StatusCode = entry.StatusCode
+	result.FinalURL = entry.FinalURL
+	result.BodySize = -1
+
+	header := make(http.Header)
+	header.Set("Content-Type", entry.ContentType)
+	header.Set("ETag", entry.ETag)
+	header.Set("Last-Modified", entry.LastModified)
+	result.Headers = responseHeaders(header)
+}
+
+// Считается ли код ответа ошибкой: 4xx/5xx всегда, а с -strict-status - любой не 2xx
+func isErrorStatus(code int) bool {
+	if code >= 400 {
+		return true
+	}
+	return options.StrictStatus && (code < 200 || code >= 300)
+}
+
+// Функция для вывода заголовков одной строкой: "Server: nginx; Cache-Control: no-cache"
+func formatHeaders(headers map[string]string) string {
+	parts := make([]string, 0, len(headers))
+	for _, name := range sortedKeys(headers) {
+		parts = append(parts, name+": "+headers[name])
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Класс ошибки: по нему решаем, стоит ли повторять запрос
+type ErrorClass string
+
+const (
+	ClassNone      ErrorClass = ""           // Ошибки нет
+	ClassDNS       ErrorClass = "dns"        // Не удалось разрешить имя хоста
+	ClassConnect   ErrorClass = "connect"    // Не удалось установить или сохранить соединение
+	ClassTLS       ErrorClass = "tls"        // Ошибка TLS/сертификата
+	ClassTimeout   ErrorClass = "timeout"    // Истек таймаут запроса
+	ClassHTTP5xx   ErrorClass = "http_5xx"   // Ошибка сервера
+	ClassHTTP429   ErrorClass = "http_429"   // Слишком много запросов
+	ClassHTTP4xx   ErrorClass = "http_4xx"   // Ошибка клиента - повтор не поможет
+	ClassHTTPOther ErrorClass = "http_other" // Ответ не 2xx без ошибки (1xx, 3xx) при -strict-status
+	ClassRedirect  ErrorClass = "redirect"   // Цикл редиректов или слишком длинная цепочка
+	ClassCancelled ErrorClass = "cancelled"  // Запуск отменен
+	ClassOther     ErrorClass = "other"      // Все остальное
+)
+
+// Ошибка для ответа с кодом 4xx/5xx
+type HTTPStatusError struct {
+	StatusCode int           // HTTP статус ответа
+	RetryAfter time.Duration // Значение заголовка Retry-After (0 - не указан)
+
+	// Retry-After больше -retry-max. Решается один раз при классификации (classifyError),
+	// чтобы текст ошибки и решение о повторе не зависели от того, когда их спросили.
+	RetryAfterTooLong bool
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.RetryAfterTooLong {
+		return fmt.Sprintf("HTTP %d %s (Retry-After %v больше -retry-max)",
+			e.StatusCode, http.StatusText(e.StatusCode), e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("HTTP %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Функция для разбора Retry-After: число секунд или HTTP-дата
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(when); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// Функция для определения класса ошибки. У ошибки HTTP заодно отмечается,
+// больше ли Retry-After, чем -retry-max (RetryAfterTooLong).
+func classifyError(err error) ErrorClass {
+	if err == nil {
+		return ClassNone
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		statusErr.RetryAfterTooLong = statusErr.RetryAfter > options.RetryMax
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return ClassHTTP429
+		case statusErr.StatusCode >= 500:
+			return ClassHTTP5xx
+		case statusErr.StatusCode < 400:
+			return ClassHTTPOther
+		default:
+			return ClassHTTP4xx
+		}
+	}
+
+	var redirectErr *RedirectError
+	if errors.As(err, &redirectErr) {
+		return ClassRedirect
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return ClassCancelled
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ClassDNS
+	}
+
+	var (
+		recordErr    tls.RecordHeaderError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &verifyErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return ClassTLS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return ClassTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return ClassConnect
+	}
+
+	return ClassOther
+}
+
+// Стоит ли повторять запрос после такой ошибки.
+// Если сервер просит подождать (Retry-After) дольше -retry-max (отмечено при классификации), не повторяем:
+// повтор раньше срока сервер все равно отклонит, а ждать часами парсер не должен.
+func isRetryable(class ErrorClass, err error) bool {
+	switch class {
+	case ClassHTTP5xx, ClassHTTP429:
+		var statusErr *HTTPStatusError
+		return !errors.As(err, &statusErr) || !statusErr.RetryAfterTooLong
+	case ClassConnect, ClassTimeout:
+		return true
+	case ClassDNS:
+		// Несуществующий домен не появится через секунду, а временный сбой резолвера - может пройти
+		var dnsErr *net.DNSError
+		return errors.As(err, &dnsErr) && !dnsErr.IsNotFound && (dnsErr.IsTemporary || dnsErr.IsTimeout)
+	default:
+		return false
+	}
+}
+
+// Пауза перед повтором: экспоненциальный рост с "полным" джиттером,
+// а если сервер прислал Retry-After - ждем столько, сколько он просит
+// (больше -retry-max не бывает: такие ответы isRetryable не повторяет)
+func retryDelay(attempt int, err error) time.Duration {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+
+	backoff := options.RetryBase << uint(attempt-1)
+	if backoff <= 0 || backoff > options.RetryMax {
+		backoff = options.RetryMax
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// Функция для парсинга с повторами при временных ошибках
+func parseWithRetry(ctx context.Context, url string) ParseResult {
+	return withRetry(ctx, url, func() ParseResult {
+		return parsePageTitle(ctx, url)
+	})
+}
+
+// Ключ контекста: сколько попыток URL уже потрачено в прошлом запуске (из контрольной точки обхода)
+type priorAttemptsKey struct{}
+
+// Функция для передачи в withRetry попыток, потраченных в прошлом запуске
+func withPriorAttempts(ctx context.Context, n int) context.Context {
+	if n <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, priorAttemptsKey{}, n)
+}
+
+// Ключ контекста: ограничения хостов, через которые идут и повторные попытки
+type hostLimitsKey struct{}
+
+// Функция для передачи в withRetry ограничений хостов пула. Повтор - такой же запрос
+// к хосту, как первая попытка, поэтому он тоже выдерживает -host-delay и Crawl-delay
+// и резервирует время начала, чтобы другие воркеры не пришли к хосту сразу за ним.
+func withHostLimits(ctx context.Context, limits *HostLimits) context.Context {
+	if limits == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, hostLimitsKey{}, limits)
+}
+
+// Функция для выполнения попытки с повторами, пока ошибка временная и попытки не кончились.
+// Попытки, потраченные в прошлом запуске (withPriorAttempts), тоже учитываются.
+// Между попытками ждем не меньше паузы хоста (-host-delay, Crawl-delay), а с ограничениями
+// хостов из контекста (withHostLimits) повтор еще и занимает очередь хоста через Start.
+func withRetry(ctx context.Context, url string, try func() ParseResult) ParseResult {
+	start := time.Now()
+	prior, _ := ctx.Value(priorAttemptsKey{}).(int)
+
+	var result ParseResult
+	for attempt := prior + 1; ; attempt++ {
+		result = try()
+		result.Attempts = attempt
+		result.ErrorClass = classifyError(result.Error)
+		if result.Status == StatusCancelled {
+			result.ErrorClass = ClassCancelled
+		}
+
+		if result.Error == nil || attempt > options.Retries || !isRetryable(result.ErrorClass, result.Error) {
+			break
+		}
+
+		// Ждем перед следующей попыткой, но не дольше, чем позволяет контекст
+		delay := retryDelay(attempt, result.Error)
+		if hostWait := hostDelay(url); hostWait > delay {
+			delay = hostWait
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			result.Status = StatusCancelled
+			result.ErrorClass = ClassCancelled
+			result.Elapsed = time.Since(start)
+			return result
+		}
+		if limits, ok := ctx.Value(hostLimitsKey{}).(*HostLimits); ok {
+			limits.Start(ctx, url)
+		}
+	}
+
+	result.Elapsed = time.Since(start)
+	return result
+}
+
+// Функция для вывода сводки ошибок по классам
+func printFailureSummary(results []ParseResult) {
+	counts := make(map[ErrorClass]int)
+	retried := 0
+	for _, result := range results {
+		if result.ErrorClass != ClassNone {
+			counts[result.ErrorClass]++
+		}
+		if result.Attempts > 1 {
+			retried++
+		}
+	}
+
+	if len(counts) == 0 && retried == 0 {
+		return
+	}
+
+	classes := make([]ErrorClass, 0, len(counts))
+	for class := range counts {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if counts[classes[i]] != counts[classes[j]] {
+			return counts[classes[i]] > counts[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+
+	if len(classes) > 0 {
+		fmt.Printf("📉 Ошибки по классам:")
+		for _, class := range classes {
+			fmt.Printf(" %s: %d", class, counts[class])
+		}
+		fmt.Println()
+	}
+	if retried > 0 {
+		fmt.Printf("🔄 URL с повторами: %d\n", retried)
+	}
+}
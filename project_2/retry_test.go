@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"0", 0},
+		{"-1", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	// Дата в будущем: пауза до нее (с точностью до секунды формата)
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got < 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %v, want около 1m", future, got)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.RetryMax = 10 * time.Second
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"503", &HTTPStatusError{StatusCode: 503}, true},
+		{"429 с Retry-After в пределах -retry-max", &HTTPStatusError{StatusCode: 429, RetryAfter: 5 * time.Second}, true},
+		{"429 с Retry-After больше -retry-max", &HTTPStatusError{StatusCode: 429, RetryAfter: time.Hour}, false},
+		{"503 с Retry-After больше -retry-max", &HTTPStatusError{StatusCode: 503, RetryAfter: time.Minute}, false},
+		{"404", &HTTPStatusError{StatusCode: 404}, false},
+		{"отмена", context.Canceled, false},
+		{"таймаут", context.DeadlineExceeded, true},
+		{"прочее", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(classifyError(tt.err), tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// Решение "Retry-After больше -retry-max" принимается при классификации и потом не меняется
+func TestRetryAfterTooLongFixedAtClassification(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.RetryMax = 10 * time.Second
+
+	err := &HTTPStatusError{StatusCode: 429, RetryAfter: time.Minute}
+	class := classifyError(err)
+	want := "HTTP 429 Too Many Requests (Retry-After 1m0s больше -retry-max)"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	options.RetryMax = time.Hour
+	if got := err.Error(); got != want {
+		t.Errorf("после смены -retry-max Error() = %q, want %q", got, want)
+	}
+	if isRetryable(class, err) {
+		t.Error("после смены -retry-max ошибка стала повторяемой")
+	}
+}
+
+func TestRetryDelayWithinLimit(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.RetryBase = 100 * time.Millisecond
+	options.RetryMax = time.Second
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		if d := retryDelay(attempt, errors.New("boom")); d < 0 || d > options.RetryMax {
+			t.Errorf("попытка %d: пауза %v вне [0, %v]", attempt, d, options.RetryMax)
+		}
+	}
+	if d := retryDelay(1, &HTTPStatusError{StatusCode: 429, RetryAfter: 700 * time.Millisecond}); d != 700*time.Millisecond {
+		t.Errorf("пауза по Retry-After: %v, want 700ms", d)
+	}
+}
+
+// Повтор - такой же запрос к хосту: он выдерживает -host-delay и не влезает
+// между запросами других воркеров к тому же хосту
+func TestRetryRespectsHostDelay(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.Retries = 2
+	options.RetryBase = time.Millisecond
+	options.RetryMax = time.Second
+	options.HostDelay = 100 * time.Millisecond
+
+	var log startLog
+	var failed atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.record("http://server" + r.URL.Path)
+		if r.URL.Path == "/flaky" && failed.CompareAndSwap(false, true) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("<title>ok</title>"))
+	}))
+	defer server.Close()
+
+	urls := []string{server.URL + "/flaky", server.URL + "/a", server.URL + "/b"}
+	sched := NewHostScheduler(urls, 2, hostDelay)
+	results, _ := runWorkerPool(context.Background(), urls, 2, sched, parseURL)
+
+	if results[0].Status != StatusOK || results[0].Attempts != 2 {
+		t.Fatalf("/flaky: статус %s, попыток %d; want ok со второй попытки", results[0].Status, results[0].Attempts)
+	}
+	// Таймер может сработать чуть раньше - допускаем погрешность
+	if gap := log.minGap("server"); gap < options.HostDelay-5*time.Millisecond {
+		t.Errorf("пауза между запросами к хосту: %v, want >= %v", gap, options.HostDelay)
+	}
+}
+
+// Функция для перехвата того, что f печатает в stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = saved }()
+
+	f()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestPrintFailureSummary(t *testing.T) {
+	retriedOK := ParseResult{Status: StatusOK, Attempts: 2}
+	failed := ParseResult{Status: StatusError, ErrorClass: ClassHTTP5xx, Attempts: 3}
+
+	tests := []struct {
+		name    string
+		results []ParseResult
+		want    string
+	}{
+		{"без ошибок и повторов", []ParseResult{{Status: StatusOK, Attempts: 1}}, ""},
+		{"все повторы удались", []ParseResult{retriedOK}, "🔄 URL с повторами: 1\n"},
+		{"ошибки и повторы", []ParseResult{retriedOK, failed}, "📉 Ошибки по классам: http_5xx: 1\n🔄 URL с повторами: 2\n"},
+		{"ошибки без повторов", []ParseResult{{Status: StatusError, ErrorClass: ClassDNS, Attempts: 1}}, "📉 Ошибки по классам: dns: 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureStdout(t, func() { printFailureSummary(tt.results) })
+			if got != tt.want {
+				t.Errorf("вывод %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
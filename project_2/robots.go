@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Максимальный размер robots.txt, который мы читаем (как у Google - 500 КиБ)
+const maxRobotsSize = 500 * 1024
+
+// Правило Allow/Disallow из robots.txt
+type robotsRule struct {
+	allow   bool           // true - Allow, false - Disallow
+	length  int            // Длина шаблона: побеждает самое длинное совпадение
+	pattern *regexp.Regexp // Шаблон пути с поддержкой * и $
+}
+
+// Правила robots.txt, относящиеся к нашему User-Agent
+type RobotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	disallowed bool // Сайт недоступен (5xx) - обходить нельзя совсем
+}
+
+// Разрешен ли путь (вместе со строкой запроса) для обхода
+func (r *RobotsRules) Allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	if r.disallowed {
+		return false
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	best := -1
+	allowed := true
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		// Самое длинное правило побеждает, при равенстве - Allow
+		if rule.length > best || (rule.length == best && rule.allow) {
+			best = rule.length
+			allowed = rule.allow
+		}
+	}
+
+	return allowed
+}
+
+// Функция для превращения шаблона robots.txt в регулярное выражение
+func compileRobotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+
+	return regexp.MustCompile(expr)
+}
+
+// Группа записей robots.txt для набора User-Agent
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// Функция для разбора robots.txt и выбора группы для нашего User-Agent
+func parseRobots(r io.Reader, userAgent string) *RobotsRules {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Несколько User-Agent подряд относятся к одной группе
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			// Пустой Disallow означает "разрешено все"
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{
+					allow:   key == "allow",
+					length:  len(value),
+					pattern: compileRobotsPattern(value),
+				})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+		lastWasAgent = false
+	}
+
+	// Имя продукта из User-Agent: "MyBot/1.0 (+url)" -> "mybot"
+	product := strings.ToLower(userAgent)
+	if idx := strings.IndexAny(product, "/ "); idx >= 0 {
+		product = product[:idx]
+	}
+
+	// Ищем группу с самым специфичным совпадением, иначе группу "*"
+	var chosen, wildcard *robotsGroup
+	bestLen := 0
+	for _, group := range groups {
+		for _, agent := range group.agents {
+			if agent == "*" {
+				if wildcard == nil {
+					wildcard = group
+				}
+				continue
+			}
+			if strings.Contains(product, agent) && len(agent) > bestLen {
+				chosen = group
+				bestLen = len(agent)
+			}
+		}
+	}
+	if chosen == nil {
+		chosen = wildcard
+	}
+
+	rules := &RobotsRules{}
+	if chosen != nil {
+		rules.rules = chosen.rules
+		rules.crawlDelay = chosen.crawlDelay
+	}
+	return rules
+}
+
+// Запись кеша: закрытый канал ready означает, что robots.txt уже загружен
+type robotsEntry struct {
+	ready chan struct{}
+	rules *RobotsRules
+}
+
+// Кеш robots.txt: каждый сайт запрашивается только один раз,
+// даже если к нему одновременно обращаются несколько воркеров
+type RobotsCache struct {
+	userAgent string
+	client    *http.Client
+	entries   map[string]*robotsEntry
+	mutex     sync.Mutex
+}
+
+// Создаем кеш robots.txt
+func NewRobotsCache(client *http.Client, userAgent string) *RobotsCache {
+	return &RobotsCache{
+		userAgent: userAgent,
+		client:    client,
+		entries:   make(map[string]*robotsEntry),
+	}
+}
+
+// Получаем правила для сайта, загружая robots.txt при первом обращении
+func (c *RobotsCache) rulesFor(ctx context.Context, parsed *url.URL) *RobotsRules {
+	origin := parsed.Scheme + "://" + parsed.Host
+
+	c.mutex.Lock()
+	entry, exists := c.entries[origin]
+	if !exists {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		c.entries[origin] = entry
+	}
+	c.mutex.Unlock()
+
+	if exists {
+		select {
+		case <-entry.ready:
+			return entry.rules
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	entry.rules = c.fetch(ctx, origin)
+
+	// Загрузку прервала отмена - не запоминаем результат, чтобы следующий запуск попробовал снова
+	if ctx.Err() != nil {
+		c.mutex.Lock()
+		delete(c.entries, origin)
+		c.mutex.Unlock()
+	}
+	close(entry.ready)
+	return entry.rules
+}
+
+// Функция для загрузки robots.txt.
+// 3xx/4xx - ограничений нет, 5xx - обход запрещен, сетевая ошибка - не мешаем основному запросу сообщить о ней.
+// 429 - сайт просит притормозить: повторяем с паузой (Retry-After или -retry-base/-retry-max),
+// а если попытки кончились или Retry-After больше -retry-max, запрещаем обход, как при 5xx.
+func (c *RobotsCache) fetch(ctx context.Context, origin string) *RobotsRules {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+		if err != nil {
+			return nil
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			defer resp.Body.Close()
+
+			switch {
+			case resp.StatusCode >= 500:
+				return &RobotsRules{disallowed: true}
+			case resp.StatusCode >= 300:
+				return nil
+			}
+			return parseRobots(io.LimitReader(resp.Body, maxRobotsSize), c.userAgent)
+		}
+
+		resp.Body.Close()
+		statusErr := &HTTPStatusError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		if attempt > options.Retries || !isRetryable(classifyError(statusErr), statusErr) {
+			return &RobotsRules{disallowed: true}
+		}
+
+		timer := time.NewTimer(retryDelay(attempt, statusErr))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		}
+	}
+}
+
+// Загружаем robots.txt сайта заранее и ждем загрузки. Планировщик вызывает это перед
+// первым запросом к хосту: иначе пауза из Crawl-delay станет известна только после
+// того, как первые запросы к хосту уже ушли.
+func (c *RobotsCache) Prefetch(ctx context.Context, rawURL string) {
+	if parsed, err := url.Parse(rawURL); err == nil {
+		c.rulesFor(ctx, parsed)
+	}
+}
+
+// Разрешен ли URL правилами robots.txt его сайта
+func (c *RobotsCache) Allowed(ctx context.Context, rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if parsed.RawQuery != "" {
+		path += "?" + parsed.RawQuery
+	}
+
+	return c.rulesFor(ctx, parsed).Allowed(path)
+}
+
+// Crawl-delay для сайта, если robots.txt уже загружен (не блокирует)
+func (c *RobotsCache) CrawlDelay(rawURL string) time.Duration {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+
+	c.mutex.Lock()
+	entry, exists := c.entries[parsed.Scheme+"://"+parsed.Host]
+	c.mutex.Unlock()
+
+	if !exists {
+		return 0
+	}
+
+	select {
+	case <-entry.ready:
+		if entry.rules != nil {
+			return entry.rules.crawlDelay
+		}
+	default:
+	}
+	return 0
+}
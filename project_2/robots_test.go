@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	const robotsTxt = `
+# Общие правила
+User-agent: *
+Disallow: /private/
+Allow: /private/public.html
+Disallow: /*.pdf$
+Disallow: /search?
+Crawl-delay: 2
+
+User-agent: OtherBot
+User-agent: MyBot
+Disallow: /
+Allow: /open/
+Crawl-delay: 0.5
+`
+
+	tests := []struct {
+		userAgent string
+		path      string
+		allowed   bool
+	}{
+		{"SomeBot/1.0", "/", true},
+		{"SomeBot/1.0", "/private/secret.html", false},
+		{"SomeBot/1.0", "/private/public.html", true}, // Более длинное правило побеждает
+		{"SomeBot/1.0", "/docs/file.pdf", false},
+		{"SomeBot/1.0", "/docs/file.pdf?x=1", true}, // $ - конец пути
+		{"SomeBot/1.0", "/search?q=go", false},
+		{"SomeBot/1.0", "/search", true},
+		{"SomeBot/1.0", "/robots.txt", true},
+		{"MyBot/2.0 (+https://example.com)", "/", false}, // Своя группа вместо "*"
+		{"MyBot/2.0 (+https://example.com)", "/open/page", true},
+		{"OtherBot", "/private/public.html", false}, // Несколько User-agent в одной группе
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userAgent+" "+tt.path, func(t *testing.T) {
+			rules := parseRobots(strings.NewReader(robotsTxt), tt.userAgent)
+			if got := rules.Allowed(tt.path); got != tt.allowed {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.path, got, tt.allowed)
+			}
+		})
+	}
+
+	if got := parseRobots(strings.NewReader(robotsTxt), "SomeBot").crawlDelay; got != 2*time.Second {
+		t.Errorf("Crawl-delay группы *: %v, want 2s", got)
+	}
+	if got := parseRobots(strings.NewReader(robotsTxt), "MyBot").crawlDelay; got != 500*time.Millisecond {
+		t.Errorf("Crawl-delay группы MyBot: %v, want 500ms", got)
+	}
+}
+
+func TestParseRobotsEmptyDisallow(t *testing.T) {
+	rules := parseRobots(strings.NewReader("User-agent: *\nDisallow:\n"), "SomeBot")
+	if !rules.Allowed("/anything") {
+		t.Error("пустой Disallow должен разрешать все")
+	}
+}
+
+func TestRobotsCacheStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		allowed bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusForbidden, true},
+		{http.StatusServiceUnavailable, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			cache := NewRobotsCache(server.Client(), "SomeBot")
+			if got := cache.Allowed(context.Background(), server.URL+"/page"); got != tt.allowed {
+				t.Errorf("robots.txt %d: Allowed = %v, want %v", tt.status, got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestRobotsCacheBacksOffOn429(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.Retries = 2
+	options.RetryBase = time.Millisecond
+	options.RetryMax = time.Second
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private/\nCrawl-delay: 1\n")
+	}))
+	defer server.Close()
+
+	cache := NewRobotsCache(server.Client(), "SomeBot")
+	if cache.Allowed(context.Background(), server.URL+"/private/x") {
+		t.Error("после повтора на 429 правила robots.txt должны соблюдаться")
+	}
+	if got := cache.CrawlDelay(server.URL + "/"); got != time.Second {
+		t.Errorf("CrawlDelay = %v, want 1s", got)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("запросов robots.txt: %d, want 2", got)
+	}
+}
+
+func TestRobotsCacheDisallowsOnPersistent429(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.Retries = 1
+	options.RetryBase = time.Millisecond
+	options.RetryMax = time.Second
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	cache := NewRobotsCache(server.Client(), "SomeBot")
+	if cache.Allowed(context.Background(), server.URL+"/page") {
+		t.Error("если robots.txt все время отвечает 429, обход сайта должен быть запрещен")
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Элементы без закрывающего тега: их не кладем в стек открытых элементов
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// Элементы, на границе которых в тексте стоит разрыв: "<p>a</p><p>b</p>" - это "a b",
+// а строчные элементы текст не разрывают: "$<b>19</b>.99" - это "$19.99"
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "td": true, "th": true, "tr": true,
+	"ul": true,
+}
+
+// Условие на атрибут: [name] или [name=value]
+type attrCondition struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// Простой селектор для одного элемента: tag#id.class[attr=value]
+type compoundSelector struct {
+	tag     string // "" или "*" - любой тег
+	id      string
+	classes []string
+	attrs   []attrCondition
+	child   bool // Перед селектором стоит ">": элемент должен быть прямым потомком предыдущего
+}
+
+// Селектор в стиле CSS: последовательность простых селекторов через пробел или ">"
+type cssSelector []compoundSelector
+
+// Функция для разбора селектора. Поддерживается подмножество CSS:
+// tag, .class, #id, [attr], [attr=value], [attr="value"], потомки через пробел и ">".
+func parseSelector(s string) (cssSelector, error) {
+	var selector cssSelector
+	child := false
+
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case isHTMLSpace(c):
+			i++
+			continue
+		case c == '>':
+			if len(selector) == 0 || child {
+				return nil, fmt.Errorf("неожиданный '>' в селекторе %q", s)
+			}
+			child = true
+			i++
+			continue
+		}
+
+		compound, n, err := parseCompound(s[i:])
+		if err != nil {
+			return nil, fmt.Errorf("селектор %q: %v", s, err)
+		}
+		compound.child = child
+		child = false
+		selector = append(selector, compound)
+		i += n
+	}
+
+	if len(selector) == 0 || child {
+		return nil, fmt.Errorf("пустой или незаконченный селектор %q", s)
+	}
+	return selector, nil
+}
+
+// Функция для разбора одного простого селектора; возвращает, сколько байт занято
+func parseCompound(s string) (compoundSelector, int, error) {
+	var compound compoundSelector
+
+	readName := func(i int) (string, int) {
+		start := i
+		for i < len(s) && (isASCIILetter(s[i]) || (s[i] >= '0' && s[i] <= '9') || s[i] == '-' || s[i] == '_' || s[i] == '*') {
+			i++
+		}
+		return s[start:i], i
+	}
+
+	i := 0
+	compound.tag, i = readName(i)
+	compound.tag = strings.ToLower(compound.tag)
+
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+		switch s[i] {
+		case '#':
+			var name string
+			name, i = readName(i + 1)
+			if name == "" {
+				return compound, 0, fmt.Errorf("пустой #id")
+			}
+			compound.id = name
+		case '.':
+			var name string
+			name, i = readName(i + 1)
+			if name == "" {
+				return compound, 0, fmt.Errorf("пустой .class")
+			}
+			compound.classes = append(compound.classes, name)
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return compound, 0, fmt.Errorf("не закрыта '['")
+			}
+			body := s[i+1 : i+end]
+			cond := attrCondition{name: strings.ToLower(strings.TrimSpace(body))}
+			if name, value, ok := strings.Cut(body, "="); ok {
+				cond.name = strings.ToLower(strings.TrimSpace(name))
+				cond.value = strings.Trim(strings.TrimSpace(value), `"'`)
+				cond.hasValue = true
+			}
+			if cond.name == "" {
+				return compound, 0, fmt.Errorf("пустое имя атрибута")
+			}
+			compound.attrs = append(compound.attrs, cond)
+			i += end + 1
+		default:
+			return compound, 0, fmt.Errorf("неожиданный символ %q", s[i])
+		}
+	}
+
+	if i == 0 {
+		return compound, 0, fmt.Errorf("пустой селектор")
+	}
+	return compound, i, nil
+}
+
+// Подходит ли элемент под простой селектор
+func (c compoundSelector) matches(token htmlToken) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != token.Name {
+		return false
+	}
+	if c.id != "" {
+		if id, _ := token.Attr("id"); id != c.id {
+			return false
+		}
+	}
+	if len(c.classes) > 0 {
+		class, _ := token.Attr("class")
+		for _, want := range c.classes {
+			if !hasToken(class, want) {
+				return false
+			}
+		}
+	}
+	for _, cond := range c.attrs {
+		value, ok := token.Attr(cond.name)
+		if !ok || (cond.hasValue && value != cond.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Подходит ли элемент под селектор с учетом его предков (stack - открытые элементы, последний - родитель)
+func (s cssSelector) matches(token htmlToken, stack []htmlToken) bool {
+	last := len(s) - 1
+	if !s[last].matches(token) {
+		return false
+	}
+	return s.matchAncestors(last, stack)
+}
+
+// Подбираем предков для селекторов s[:i] (s[i] уже совпал с элементом, чьи предки - stack)
+func (s cssSelector) matchAncestors(i int, stack []htmlToken) bool {
+	if i == 0 {
+		return true
+	}
+
+	if s[i].child {
+		// Прямой потомок: проверяем только родителя
+		if len(stack) == 0 || !s[i-1].matches(stack[len(stack)-1]) {
+			return false
+		}
+		return s.matchAncestors(i-1, stack[:len(stack)-1])
+	}
+
+	// Любой предок: пробуем всех, начиная с ближайшего
+	for j := len(stack) - 1; j >= 0; j-- {
+		if s[i-1].matches(stack[j]) && s.matchAncestors(i-1, stack[:j]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Значение, которое собирается для найденного элемента
+type selectorCapture struct {
+	depth int             // Длина стека, при которой элемент закрывается
+	text  strings.Builder // Текст элемента и его потомков
+}
+
+// Функция для выбора значений по селектору: текст элементов или значение атрибута attr.
+// limit - сколько значений нужно (0 - все).
+func selectValues(r io.Reader, selector cssSelector, attr string, limit int) ([]string, error) {
+	tokenizer := newHTMLTokenizer(r)
+
+	var (
+		values   []string
+		stack    []htmlToken
+		captures []*selectorCapture
+	)
+	finish := func(c *selectorCapture) {
+		values = append(values, collapseSpaces(c.text.String()))
+	}
+
+	for limit == 0 || len(values) < limit {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return values, err
+		}
+
+		// Граница блочного элемента разрывает текст всех захваченных элементов
+		if (token.Type == startTagToken || token.Type == endTagToken) && blockElements[token.Name] {
+			for _, c := range captures {
+				c.text.WriteString(" ")
+			}
+		}
+
+		switch token.Type {
+		case startTagToken:
+			if selector.matches(token, stack) {
+				if attr != "" {
+					if value, ok := token.Attr(attr); ok {
+						values = append(values, strings.TrimSpace(value))
+					}
+				} else if voidElements[token.Name] || token.SelfClosing {
+					values = append(values, "")
+				} else {
+					captures = append(captures, &selectorCapture{depth: len(stack)})
+				}
+			}
+			if !voidElements[token.Name] && !token.SelfClosing {
+				stack = append(stack, token)
+			}
+
+		case endTagToken:
+			// Закрываем элемент и все незакрытые внутри него (например, <p> без </p>)
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].Name == token.Name {
+					stack = stack[:i]
+					break
+				}
+			}
+			for len(captures) > 0 && captures[len(captures)-1].depth >= len(stack) {
+				finish(captures[len(captures)-1])
+				captures = captures[:len(captures)-1]
+			}
+
+		case textToken:
+			// Код скриптов и стилей - не текст страницы
+			if len(stack) > 0 && rawTextElements[stack[len(stack)-1].Name] && !rcdataElements[stack[len(stack)-1].Name] {
+				continue
+			}
+			// Текст пишем как есть: пробелы между словами уже есть в самом тексте
+			for _, c := range captures {
+				c.text.WriteString(token.Data)
+			}
+		}
+	}
+
+	// Незакрытые элементы в конце документа
+	for i := len(captures) - 1; i >= 0 && (limit == 0 || len(values) < limit); i-- {
+		finish(captures[i])
+	}
+
+	if limit > 0 && len(values) > limit {
+		values = values[:limit]
+	}
+	return values, nil
+}
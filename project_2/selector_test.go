@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectValues(t *testing.T) {
+	const page = `<html><body>
+<div id="main" class="product card">
+  <h2>Телефон</h2>
+  <span class="price">$<b>19</b>.99</span>
+  <a href="/buy" data-id="42">Купить</a>
+  <ul><li>один</li><li>два</li></ul>
+</div>
+<div class="card"><p>Первый</p><p>Второй<br>абзац</p></div>
+<p>Hello <em>wor</em>ld</p>
+<script>var s = "<p>не текст</p>";</script>
+</body></html>`
+
+	tests := []struct {
+		name     string
+		selector string
+		attr     string
+		limit    int
+		want     []string
+	}{
+		{name: "строчный тег не разрывает текст", selector: ".price", want: []string{"$19.99"}},
+		{name: "часть слова в <em>", selector: "body > p", want: []string{"Hello world"}},
+		{name: "блочные элементы разделяются пробелом", selector: "div.card",
+			want: []string{"Телефон $19.99 Купить один два", "Первый Второй абзац"}},
+		{name: "id и класс", selector: "#main.product h2", want: []string{"Телефон"}},
+		{name: "атрибут", selector: "a[data-id=42]", attr: "href", want: []string{"/buy"}},
+		{name: "атрибут в кавычках", selector: `a[data-id="42"]`, want: []string{"Купить"}},
+		{name: "прямой потомок", selector: "ul > li", want: []string{"один", "два"}},
+		{name: "нет прямого потомка", selector: "div > li", want: nil},
+		{name: "limit", selector: "li", limit: 1, want: []string{"один"}},
+		{name: "код скрипта - не текст", selector: "script", want: []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := parseSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("parseSelector(%q): %v", tt.selector, err)
+			}
+			got, err := selectValues(strings.NewReader(page), selector, tt.attr, tt.limit)
+			if err != nil {
+				t.Fatalf("selectValues: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectValues(%q)\n got: %q\nwant: %q", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, s := range []string{"", "> p", "div >", "div > > p", "p.", "#", "a[", "a[=x]", "p!"} {
+		if _, err := parseSelector(s); err == nil {
+			t.Errorf("parseSelector(%q): ожидалась ошибка", s)
+		}
+	}
+}
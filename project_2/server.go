@@ -0,0 +1,665 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Сколько хранить завершенное задание, прежде чем забыть его результаты
+const jobTTL = time.Hour
+
+// Наибольший размер тела POST /parse
+const maxParseRequest = 10 << 20
+
+// Состояние задания
+const (
+	JobRunning   = "running"   // URL еще обрабатываются
+	JobDone      = "done"      // Все URL обработаны
+	JobCancelled = "cancelled" // Задание отменено: необработанные URL помечены как отмененные
+)
+
+// Задание сервиса: список URL, который обрабатывает общий пул воркеров
+type Job struct {
+	ID      string
+	URLs    []string
+	Created time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	sched  *HostScheduler // Очереди URL задания по хостам (ограничения хостов общие для сервиса)
+
+	mutex     sync.Mutex
+	results   []ParseResult // Результаты по позициям входного списка
+	ready     []bool
+	arrival   []int // Позиции в порядке готовности (для потоковой выдачи)
+	ok        int
+	failed    int
+	skipped   int
+	cancelled bool
+	finished  time.Time
+	changed   chan struct{} // Закрывается при каждом новом результате
+}
+
+// Функция для создания задания: его URL подчиняются общим для сервиса ограничениям хостов
+func newJob(ctx context.Context, id string, urls []string, limits *HostLimits) *Job {
+	job := &Job{
+		ID:      id,
+		URLs:    urls,
+		Created: time.Now(),
+		sched:   NewSharedHostScheduler(urls, limits),
+		results: make([]ParseResult, len(urls)),
+		ready:   make([]bool, len(urls)),
+		changed: make(chan struct{}),
+	}
+	job.ctx, job.cancel = context.WithCancel(withHostLimits(ctx, limits))
+	return job
+}
+
+// Записываем результат. Возвращает true, если это был последний URL задания.
+func (j *Job) add(index int, result ParseResult) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.ready[index] {
+		return false
+	}
+	j.results[index] = result
+	j.ready[index] = true
+	j.arrival = append(j.arrival, index)
+	switch result.Status {
+	case StatusOK:
+		j.ok++
+	case StatusError:
+		j.failed++
+	default:
+		j.skipped++
+	}
+
+	// Будим всех, кто ждет новых результатов
+	close(j.changed)
+	j.changed = make(chan struct{})
+
+	if len(j.arrival) < len(j.URLs) {
+		return false
+	}
+	j.finished = time.Now()
+	j.cancel()
+	return true
+}
+
+// Завершено ли задание раньше, чем jobTTL назад
+func (j *Job) expired() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return !j.finished.IsZero() && time.Since(j.finished) > jobTTL
+}
+
+// Состояние задания (вызывать под мьютексом задания)
+func (j *Job) statusLocked() string {
+	switch {
+	case j.cancelled:
+		return JobCancelled
+	case len(j.arrival) == len(j.URLs):
+		return JobDone
+	default:
+		return JobRunning
+	}
+}
+
+// Результаты, готовые после первых from, и завершено ли задание.
+// Если новых результатов нет, канал закроется, когда они появятся.
+func (j *Job) resultsSince(from int) ([]ParseResult, bool, <-chan struct{}) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	var results []ParseResult
+	for _, index := range j.arrival[from:] {
+		results = append(results, j.results[index])
+	}
+	return results, len(j.arrival) == len(j.URLs), j.changed
+}
+
+// Описание задания в ответах сервиса
+type jobRecord struct {
+	ID        string         `json:"id"`
+	Status    string         `json:"status"`
+	Created   time.Time      `json:"created"`
+	ElapsedMs float64        `json:"elapsed_ms"`
+	Total     int            `json:"total"`
+	Done      int            `json:"done"`
+	OK        int            `json:"ok"`
+	Failed    int            `json:"failed"`
+	Skipped   int            `json:"skipped"`
+	Results   []resultRecord `json:"results,omitempty"` // Готовые результаты в порядке входного списка
+}
+
+// Описание задания (withResults - вместе с готовыми результатами)
+func (j *Job) record(withResults bool) jobRecord {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	end := j.finished
+	if end.IsZero() {
+		end = time.Now()
+	}
+	record := jobRecord{
+		ID:        j.ID,
+		Status:    j.statusLocked(),
+		Created:   j.Created,
+		ElapsedMs: float64(end.Sub(j.Created).Microseconds()) / 1000,
+		Total:     len(j.URLs),
+		Done:      len(j.arrival),
+		OK:        j.ok,
+		Failed:    j.failed,
+		Skipped:   j.skipped,
+	}
+	if withResults {
+		for i, result := range j.results {
+			if j.ready[i] {
+				record.Results = append(record.Results, toRecord(result))
+			}
+		}
+	}
+	return record
+}
+
+// Очередь заданий с честным планированием: воркеры берут URL у заданий по кругу,
+// поэтому маленькое задание не ждет, пока закончится большое, пришедшее раньше.
+// Внутри задания URL выдает его планировщик хостов, а -per-host и -host-delay
+// проверяются по общим для всех заданий ограничениям: два задания с одним сайтом
+// вместе не превысят их.
+type JobQueue struct {
+	mutex  sync.Mutex
+	active []*Job
+	cursor int           // С какого задания начинать следующий поиск
+	wake   chan struct{} // Сигнал "появилось задание или освободился хост"
+}
+
+// Создаем пустую очередь
+func NewJobQueue() *JobQueue {
+	return &JobQueue{wake: make(chan struct{}, 1)}
+}
+
+// Добавляем задание в круг
+func (q *JobQueue) Add(job *Job) {
+	q.mutex.Lock()
+	q.active = append(q.active, job)
+	q.mutex.Unlock()
+	q.notify()
+}
+
+// Убираем задание из круга (завершено или отменено)
+func (q *JobQueue) Remove(job *Job) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, active := range q.active {
+		if active == job {
+			q.active = append(q.active[:i], q.active[i+1:]...)
+			if q.cursor > i {
+				q.cursor--
+			}
+			break
+		}
+	}
+	if q.cursor >= len(q.active) {
+		q.cursor = 0
+	}
+}
+
+// Будим воркер, который ждет работы
+func (q *JobQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Следующий URL для воркера: задания обходятся по кругу, начиная со следующего
+// после того, которому достался прошлый URL. Ждет, пока работа появится; false - ctx отменен.
+func (q *JobQueue) Next(ctx context.Context) (*Job, poolJob, bool) {
+	for {
+		job, task, wakeAt, ok := q.next(time.Now())
+		if ok {
+			// Работа может быть и для других воркеров
+			q.notify()
+			return job, task, true
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !wakeAt.IsZero() {
+			timer = time.NewTimer(time.Until(wakeAt))
+			timeout = timer.C
+		}
+
+		select {
+		case <-q.wake:
+		case <-timeout:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return nil, poolJob{}, false
+		}
+	}
+}
+
+// Ищем URL, который можно отдать прямо сейчас, а если его нет - когда проверить снова
+func (q *JobQueue) next(now time.Time) (*Job, poolJob, time.Time, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var wakeAt time.Time
+	for i := 0; i < len(q.active); i++ {
+		idx := (q.cursor + i) % len(q.active)
+		job := q.active[idx]
+
+		task, jobWakeAt, ok := job.sched.next(now)
+		if ok {
+			q.cursor = (idx + 1) % len(q.active)
+			return job, task, time.Time{}, true
+		}
+		if !jobWakeAt.IsZero() && (wakeAt.IsZero() || jobWakeAt.Before(wakeAt)) {
+			wakeAt = jobWakeAt
+		}
+	}
+	return nil, poolJob{}, wakeAt, false
+}
+
+// Сервис парсера: задания, общий пул воркеров и HTTP API
+type ParseService struct {
+	ctx     context.Context
+	queue   *JobQueue
+	limits  *HostLimits // Ограничения хостов для всех заданий
+	workers sync.WaitGroup
+
+	mutex sync.Mutex
+	jobs  map[string]*Job
+}
+
+// Функция для создания сервиса и запуска пула из workers воркеров.
+// Воркеры завершаются при отмене ctx.
+func NewParseService(ctx context.Context, workers int) *ParseService {
+	s := &ParseService{
+		ctx:    ctx,
+		queue:  NewJobQueue(),
+		limits: NewHostLimits(options.PerHost, hostDelay),
+		jobs:   make(map[string]*Job),
+	}
+	for i := 0; i < workers; i++ {
+		s.workers.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// Воркер общего пула: берет URL у заданий по очереди и записывает результат в задание
+func (s *ParseService) worker() {
+	defer s.workers.Done()
+
+	for {
+		job, task, ok := s.queue.Next(s.ctx)
+		if !ok {
+			return
+		}
+
+		job.sched.Start(job.ctx, task.url)
+		result := parseURL(job.ctx, task.url)
+		job.sched.Release(task.url)
+		if job.add(task.index, result) {
+			s.queue.Remove(job)
+			record := job.record(false)
+			logf("🏁 Задание %s: %s за %v (✅ %d, ❌ %d, ⏭️  %d)\n", job.ID, record.Status,
+				job.finished.Sub(job.Created).Round(time.Millisecond), record.OK, record.Failed, record.Skipped)
+		}
+		s.queue.notify()
+	}
+}
+
+// Функция для создания задания и постановки его в очередь
+func (s *ParseService) Submit(urls []string) *Job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Заодно забываем давно завершенные задания, чтобы память сервиса не росла
+	for id, job := range s.jobs {
+		if job.expired() {
+			delete(s.jobs, id)
+		}
+	}
+
+	job := newJob(s.ctx, newJobID(), urls, s.limits)
+	s.jobs[job.ID] = job
+	s.queue.Add(job)
+	logf("📥 Задание %s: %d URL\n", job.ID, len(urls))
+	return job
+}
+
+// Отменяем задание: невыданные URL сразу помечаются как отмененные,
+// а выполняющиеся запросы прерываются через контекст задания
+func (s *ParseService) Cancel(job *Job) {
+	s.queue.Remove(job)
+
+	job.mutex.Lock()
+	running := job.statusLocked() == JobRunning
+	if running {
+		job.cancelled = true
+	}
+	job.mutex.Unlock()
+	if !running {
+		return
+	}
+
+	job.cancel()
+	for _, task := range job.sched.Remaining() {
+		job.add(task.index, cancelledResult(task.url))
+	}
+	logf("⏹️  Задание %s отменено\n", job.ID)
+}
+
+// Задание по ID
+func (s *ParseService) Job(id string) (*Job, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	job, ok := s.jobs[id]
+	return job, ok
+}
+
+// Отменяем все задания (при остановке сервиса), чтобы потоковые ответы завершились
+func (s *ParseService) CancelAll() {
+	s.mutex.Lock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mutex.Unlock()
+
+	for _, job := range jobs {
+		s.Cancel(job)
+	}
+}
+
+// Ждем завершения воркеров (после отмены ctx сервиса)
+func (s *ParseService) Wait() {
+	s.workers.Wait()
+}
+
+// Функция для создания случайного ID задания
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Маршруты HTTP API:
+//
+//	POST /parse              - новое задание: {"urls": [...]} или URL по одному на строку
+//	GET  /jobs               - список заданий
+//	GET  /jobs/{id}          - ход задания и готовые результаты
+//	GET  /jobs/{id}/stream   - результаты в NDJSON по мере готовности
+//	POST /jobs/{id}/cancel   - отменить задание (DELETE /jobs/{id} - то же самое)
+func (s *ParseService) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/parse", s.handleParse)
+	mux.HandleFunc("/jobs", s.handleJobs)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	return mux
+}
+
+// Запрос на создание задания в JSON
+type parseRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// Ответ на создание задания
+type parseResponse struct {
+	ID        string          `json:"id,omitempty"`
+	URLs      int             `json:"urls"`
+	Skipped   []skippedRecord `json:"skipped,omitempty"`
+	StatusURL string          `json:"status_url,omitempty"`
+	StreamURL string          `json:"stream_url,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// Пропущенная строка списка URL в ответах сервиса
+type skippedRecord struct {
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+// Функция для преобразования пропущенных строк в записи ответа
+func toSkippedRecords(skipped []SkippedURL) []skippedRecord {
+	var records []skippedRecord
+	for _, s := range skipped {
+		records = append(records, skippedRecord{URL: s.URL, Reason: s.Reason})
+	}
+	return records
+}
+
+// POST /parse
+func (s *ParseService) handleParse(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxParseRequest)
+	var raw []string
+	media, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if media == "application/json" {
+		var request parseRequest
+		dec := json.NewDecoder(body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("некорректный JSON: %v", err))
+			return
+		}
+		raw = request.URLs
+	} else {
+		var err error
+		if raw, err = readURLLines(body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	urls, skipped := cleanURLs(raw)
+	if len(urls) == 0 {
+		writeJSON(w, http.StatusBadRequest, parseResponse{
+			Skipped: toSkippedRecords(skipped),
+			Error:   "нет ни одного корректного URL",
+		})
+		return
+	}
+
+	job := s.Submit(urls)
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, parseResponse{
+		ID:        job.ID,
+		URLs:      len(urls),
+		Skipped:   toSkippedRecords(skipped),
+		StatusURL: "/jobs/" + job.ID,
+		StreamURL: "/jobs/" + job.ID + "/stream",
+	})
+}
+
+// GET /jobs
+func (s *ParseService) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	s.mutex.Lock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mutex.Unlock()
+
+	records := make([]jobRecord, 0, len(jobs))
+	for _, job := range jobs {
+		records = append(records, job.record(false))
+	}
+	// Сначала новые задания
+	sort.Slice(records, func(i, k int) bool {
+		return records[i].Created.After(records[k].Created)
+	})
+	writeJSON(w, http.StatusOK, records)
+}
+
+// GET /jobs/{id}, GET /jobs/{id}/stream, POST /jobs/{id}/cancel, DELETE /jobs/{id}
+func (s *ParseService) handleJob(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+	job, ok := s.Job(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("задание %q не найдено", id))
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodDelete:
+		s.Cancel(job)
+		writeJSON(w, http.StatusOK, job.record(false))
+	case action == "":
+		if allowMethod(w, r, http.MethodGet, http.MethodDelete) {
+			writeJSON(w, http.StatusOK, job.record(true))
+		}
+	case action == "stream":
+		if allowMethod(w, r, http.MethodGet) {
+			s.streamJob(w, r, job)
+		}
+	case action == "cancel":
+		if allowMethod(w, r, http.MethodPost) {
+			s.Cancel(job)
+			writeJSON(w, http.StatusOK, job.record(false))
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("неизвестное действие %q", action))
+	}
+}
+
+// Потоковая выдача результатов задания в NDJSON: сначала уже готовые, затем новые по мере
+// готовности. Ответ заканчивается, когда готовы все URL задания или клиент отключился.
+func (s *ParseService) streamJob(w http.ResponseWriter, r *http.Request, job *Job) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	sent := 0
+	for {
+		results, finished, changed := job.resultsSince(sent)
+		for _, result := range results {
+			if err := enc.Encode(toRecord(result)); err != nil {
+				return
+			}
+		}
+		sent += len(results)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if finished {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Проверяем метод запроса; если он не подходит - отвечаем 405
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	if contains(methods, r.Method) {
+		return true
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("метод %s не поддерживается", r.Method))
+	return false
+}
+
+// Функция для ответа в JSON
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		logf("❌ Ошибка ответа: %v\n", err)
+	}
+}
+
+// Функция для ответа с ошибкой: {"error": "..."}
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// Слушает ли адрес только локальные подключения (127.0.0.1, ::1, localhost).
+// Пустой хост (":8080") - все интерфейсы.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Функция для запуска сервиса парсера на addr. Работает, пока не отменен ctx
+// (Ctrl-C): тогда задания отменяются, а сервер дожидается открытых ответов.
+func serve(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	service := NewParseService(ctx, options.Workers)
+	server := &http.Server{
+		Handler:           service.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Serve возвращается сразу после начала Shutdown, а открытые ответы
+	// дописываются позже - ждем их через stopped
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		service.CancelAll()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logf("🛰️  Сервис парсера: http://%s (воркеров: %d, на хост: %d)\n",
+		listener.Addr(), options.Workers, options.PerHost)
+	logf("   POST /parse, GET /jobs/{id}, GET /jobs/{id}/stream, POST /jobs/{id}/cancel\n\n")
+
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		<-stopped
+		err = nil
+	}
+	service.Wait()
+	return err
+}
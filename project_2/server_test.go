@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Функция для списка из n URL одного хоста
+func hostURLs(host string, n int) []string {
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("http://%s/%d", host, i+1)
+	}
+	return urls
+}
+
+// Маленькое задание, пришедшее позже, не ждет окончания большого
+func TestJobQueueFairness(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.PerHost = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewParseService(ctx, 0)
+	big := service.Submit(hostURLs("big", 5))
+	small := service.Submit(hostURLs("small", 2))
+
+	var order []string
+	for {
+		job, task, _, ok := service.queue.next(time.Now())
+		if !ok {
+			break
+		}
+		if job != big && job != small {
+			t.Fatalf("URL %s от неизвестного задания", task.url)
+		}
+		order = append(order, hostKey(task.url))
+	}
+
+	want := "big small big small big big big"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("порядок выдачи: %s, want %s", got, want)
+	}
+}
+
+func TestParseServiceCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewParseService(ctx, 0)
+	job := service.Submit(hostURLs("a", 3))
+
+	// Один URL уже выдан воркеру, два ждут в очереди
+	running, task, _, ok := service.queue.next(time.Now())
+	if !ok || running != job {
+		t.Fatal("очередь не выдала URL задания")
+	}
+
+	service.Cancel(job)
+	if job.ctx.Err() == nil {
+		t.Error("контекст задания не отменен: выполняющийся запрос не прервется")
+	}
+	record := job.record(true)
+	if record.Status != JobCancelled || record.Done != 2 || record.Skipped != 2 {
+		t.Errorf("после отмены: %s, готово %d, пропущено %d; want cancelled, 2, 2", record.Status, record.Done, record.Skipped)
+	}
+	for _, result := range record.Results {
+		if result.Status != string(StatusCancelled) {
+			t.Errorf("%s: статус %s, want cancelled", result.URL, result.Status)
+		}
+	}
+	if _, _, _, ok := service.queue.next(time.Now()); ok {
+		t.Error("отмененное задание осталось в очереди")
+	}
+
+	// Воркер дописывает прерванный URL - задание остается отмененным
+	job.add(task.index, cancelledResult(task.url))
+	if record := job.record(false); record.Status != JobCancelled || record.Done != 3 {
+		t.Errorf("после ответа воркера: %s, готово %d", record.Status, record.Done)
+	}
+
+	// Повторная отмена ничего не меняет
+	service.Cancel(job)
+	if record := job.record(false); record.Done != 3 {
+		t.Errorf("после повторной отмены готово %d", record.Done)
+	}
+}
+
+func TestParseServiceHTTP(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options.PerHost = 2
+
+	fixture := newTestFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	service := NewParseService(ctx, 3)
+	defer service.Wait()
+	defer cancel()
+	api := httptest.NewServer(service.Handler())
+	defer api.Close()
+
+	body := fmt.Sprintf(`{"urls": ["%[1]s/page/1", "%[1]s/page/2", "%[1]s/page/3?status=404", "not a url"]}`, fixture.URL)
+	resp, err := http.Post(api.URL+"/parse", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created parseResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || created.ID == "" || created.URLs != 3 || len(created.Skipped) != 1 {
+		t.Fatalf("POST /parse: %d %+v", resp.StatusCode, created)
+	}
+
+	// Поток отдает каждый результат по готовности и заканчивается вместе с заданием
+	resp, err = http.Get(api.URL + created.StreamURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[string]string)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var record resultRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("строка потока %q: %v", scanner.Text(), err)
+		}
+		statuses[strings.TrimPrefix(record.URL, fixture.URL)] = record.Status
+	}
+	resp.Body.Close()
+	if len(statuses) != 3 || statuses["/page/1"] != "ok" || statuses["/page/3?status=404"] != "error" {
+		t.Errorf("результаты потока: %v", statuses)
+	}
+
+	resp, err = http.Get(api.URL + created.StatusURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record jobRecord
+	json.NewDecoder(resp.Body).Decode(&record)
+	resp.Body.Close()
+	if record.Status != JobDone || record.OK != 2 || record.Failed != 1 || len(record.Results) != 3 {
+		t.Errorf("GET %s: %+v", created.StatusURL, record)
+	}
+	// Результаты задания - в порядке входного списка
+	if len(record.Results) == 3 && !strings.HasSuffix(record.Results[0].URL, "/page/1") {
+		t.Errorf("первый результат %s, want /page/1", record.Results[0].URL)
+	}
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/jobs/unknown", http.StatusNotFound},
+		{http.MethodGet, "/parse", http.StatusMethodNotAllowed},
+		{http.MethodGet, created.StatusURL + "/cancel", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/jobs", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, api.URL+tt.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s: %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Максимальная глубина вложенности индексов sitemap
+const maxSitemapDepth = 3
+
+// URL, который не попал в список для парсинга, и причина
+type SkippedURL struct {
+	URL    string // Исходная строка
+	Reason string // Почему URL пропущен
+}
+
+// Элемент <url> из sitemap.xml
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+// Корневой элемент sitemap: либо <urlset>, либо <sitemapindex>
+type sitemapDocument struct {
+	XMLName  xml.Name     `xml:""`
+	URLs     []sitemapURL `xml:"url"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// Функция для чтения URL построчно (пустые строки и комментарии # пропускаются)
+func readURLLines(r io.Reader) ([]string, error) {
+	var urls []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+
+	return urls, scanner.Err()
+}
+
+// Функция для чтения URL из файла ("-" означает stdin)
+func readURLFile(path string) ([]string, error) {
+	if path == "-" {
+		return readURLLines(os.Stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readURLLines(file)
+}
+
+// Функция для загрузки sitemap по URL или из локального файла.
+// Запрос привязан к контексту: Ctrl-C прерывает и загрузку sitemap.
+func fetchSitemap(ctx context.Context, location string) ([]byte, error) {
+	var data []byte
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		// Заголовки, авторизация, cookies и прокси - те же, что и для страниц
+		client := &http.Client{Timeout: 30 * time.Second, Transport: httpClient.Transport, Jar: httpClient.Jar}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("sitemap %s: HTTP %d", location, resp.StatusCode)
+		}
+
+		data, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		data, err = os.ReadFile(location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Sitemap часто отдают сжатым (sitemap.xml.gz)
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		return io.ReadAll(reader)
+	}
+
+	return data, nil
+}
+
+// Функция для получения всех URL из sitemap (индексы sitemap обходятся рекурсивно)
+func loadSitemap(ctx context.Context, location string) ([]string, error) {
+	visited := make(map[string]bool)
+	return loadSitemapDepth(ctx, location, 0, visited)
+}
+
+func loadSitemapDepth(ctx context.Context, location string, depth int, visited map[string]bool) ([]string, error) {
+	if depth > maxSitemapDepth {
+		return nil, fmt.Errorf("sitemap %s: превышена глубина вложенности (%d)", location, maxSitemapDepth)
+	}
+	if visited[location] {
+		return nil, nil
+	}
+	visited[location] = true
+
+	data, err := fetchSitemap(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("sitemap %s: %v", location, err)
+	}
+
+	var urls []string
+	switch doc.XMLName.Local {
+	case "urlset":
+		for _, u := range doc.URLs {
+			urls = append(urls, strings.TrimSpace(u.Loc))
+		}
+	case "sitemapindex":
+		for _, s := range doc.Sitemaps {
+			nested, err := loadSitemapDepth(ctx, strings.TrimSpace(s.Loc), depth+1, visited)
+			if err != nil {
+				logf("⚠️  Не удалось загрузить вложенный sitemap: %v\n", err)
+				continue
+			}
+			urls = append(urls, nested...)
+		}
+	default:
+		return nil, fmt.Errorf("sitemap %s: неизвестный корневой элемент <%s>", location, doc.XMLName.Local)
+	}
+
+	return urls, nil
+}
+
+// Функция для проверки URL: нужна схема http/https и хост
+func validateURL(raw string) (string, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("не удалось разобрать URL")
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("неподдерживаемая схема %q", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("не указан хост")
+	}
+
+	// Фрагмент не отправляется на сервер, поэтому не влияет на уникальность
+	parsed.Fragment = ""
+	return parsed.String(), nil
+}
+
+// Функция для очистки списка URL: проверка и удаление дубликатов с сохранением порядка
+func cleanURLs(raw []string) ([]string, []SkippedURL) {
+	seen := make(map[string]bool)
+	var urls []string
+	var skipped []SkippedURL
+
+	for _, candidate := range raw {
+		normalized, err := validateURL(candidate)
+		if err != nil {
+			skipped = append(skipped, SkippedURL{URL: candidate, Reason: err.Error()})
+			continue
+		}
+		if seen[normalized] {
+			skipped = append(skipped, SkippedURL{URL: candidate, Reason: "дубликат"})
+			continue
+		}
+		seen[normalized] = true
+		urls = append(urls, normalized)
+	}
+
+	return urls, skipped
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSitemapIndex(t *testing.T) {
+	server := newTestFixture(t)
+
+	// Индекс со ссылкой на sitemap тестового сервера и на самого себя (цикл не обходится дважды)
+	mux := http.NewServeMux()
+	index := httptest.NewServer(mux)
+	defer index.Close()
+	mux.HandleFunc("/index.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%s/sitemap.xml</loc></sitemap><sitemap><loc>%s/index.xml</loc></sitemap></sitemapindex>`,
+			server.URL, index.URL)
+	})
+
+	urls, err := loadSitemap(context.Background(), index.URL+"/index.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != defaultFixtureOptions.Pages {
+		t.Fatalf("из sitemap получено %d URL, want %d страниц тестового сервера", len(urls), defaultFixtureOptions.Pages)
+	}
+	if !strings.HasPrefix(urls[0], server.URL+"/page/") {
+		t.Errorf("первый URL %q, want страницу тестового сервера", urls[0])
+	}
+}
+
+// Ctrl-C прерывает загрузку sitemap, а не ждет таймаута клиента
+func TestLoadSitemapCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := loadSitemap(ctx, server.URL+"/sitemap.xml"); err == nil {
+		t.Error("ожидалась ошибка после отмены")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("загрузка прервана через %v после отмены", elapsed)
+	}
+}
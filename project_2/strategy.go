@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Стратегия конкурентной обработки списка URL. Все стратегии делают одну и ту же работу
+// (robots.txt, повторы, разбор страницы) и отличаются только тем, как запускают горутины.
+// Результаты возвращаются в порядке входного списка (с -unordered - в порядке готовности).
+type Strategy interface {
+	Name() string        // Имя для флага -strategy
+	Description() string // Что это за подход, одной строкой
+	Run(ctx context.Context, urls []string) ([]ParseResult, time.Duration)
+}
+
+// Функция для создания всех стратегий в порядке от простой к сложной.
+// Конвейеру нужны настройки этапов (-stages).
+func newStrategies(stageConfigs map[string]StageConfig) []Strategy {
+	return []Strategy{
+		sequentialStrategy{},
+		goroutinesStrategy{},
+		poolStrategy{},
+		semaphoreStrategy{},
+		errGroupStrategy{},
+		pipelineStrategy{configs: stageConfigs},
+	}
+}
+
+// Функция для поиска стратегии по имени
+func findStrategy(strategies []Strategy, name string) (Strategy, error) {
+	var names []string
+	for _, s := range strategies {
+		if s.Name() == name {
+			return s, nil
+		}
+		names = append(names, s.Name())
+	}
+	return nil, fmt.Errorf("неизвестная стратегия %q, доступны: %s", name, strings.Join(names, ", "))
+}
+
+// Последовательная обработка: один URL за другим
+type sequentialStrategy struct{}
+
+func (sequentialStrategy) Name() string { return "sequential" }
+func (sequentialStrategy) Description() string {
+	return "по одному URL, без горутин"
+}
+
+func (sequentialStrategy) Run(ctx context.Context, urls []string) ([]ParseResult, time.Duration) {
+	return parseSequential(ctx, urls)
+}
+
+// Горутина на каждый URL без ограничений: самый простой параллельный код,
+// но для 100 000 URL это 100 000 горутин и столько же соединений сразу.
+// -workers и -per-host не соблюдаются (пауза -host-delay - тоже), поэтому только для своих серверов.
+type goroutinesStrategy struct{}
+
+func (goroutinesStrategy) Name() string { return "goroutines" }
+func (goroutinesStrategy) Description() string {
+	return "горутина на каждый URL, без ограничений"
+}
+
+func (goroutinesStrategy) Run(ctx context.Context, urls []string) ([]ParseResult, time.Duration) {
+	logf("🌪️  Запуск горутины на каждый URL (%d горутин одновременно)...\n", len(urls))
+	start := time.Now()
+	p := startProgress("Горутины", len(urls), len(urls))
+
+	resultsChan := make(chan indexedResult)
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+
+		go func(i int, url string) {
+			defer wg.Done()
+			resultsChan <- indexedResult{index: i, result: strategyWork(ctx, p, url)}
+		}(i, url)
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultsChan)
+	}()
+
+	collector := newResultCollector(len(urls), options.Unordered)
+	for item := range resultsChan {
+		collector.add(item)
+	}
+	p.Stop()
+
+	elapsed := time.Since(start)
+	logf("✅ Горутины завершены за: %v\n\n", elapsed)
+	return collector.results(), elapsed
+}
+
+// Пул воркеров: -workers горутин забирают URL из общей очереди (см. runWorkerPool)
+type poolStrategy struct{}
+
+func (poolStrategy) Name() string { return "pool" }
+func (poolStrategy) Description() string {
+	return "пул из -workers воркеров с общей очередью"
+}
+
+func (poolStrategy) Run(ctx context.Context, urls []string) ([]ParseResult, time.Duration) {
+	return parseParallel(ctx, urls)
+}
+
+// Семафор на буферизованном канале: горутина создается на каждый URL, но не раньше,
+// чем в канале освободится место. Горутин одновременно не больше -workers,
+// и в отличие от пула каждая живет ровно одну задачу.
+type semaphoreStrategy struct{}
+
+func (semaphoreStrategy) Name() string { return "semaphore" }
+func (semaphoreStrategy) Description() string {
+	return "горутина на URL, не больше -workers (буферизованный канал)"
+}
+
+func (semaphoreStrategy) Run(ctx context.Context, urls []string) ([]ParseResult, time.Duration) {
+	logf("🚦 Запуск с семафором (одновременно не больше %d горутин, на хост: %d)...\n",
+		options.Workers, options.PerHost)
+	start := time.Now()
+	p := startProgress("Семафор", len(urls), len(urls))
+
+	// Ограничения по хостам те же, что у пула: URL выдает планировщик
+	sched := NewHostScheduler(urls, options.PerHost, hostDelay)
+	jobs := make(chan poolJob)
+	go sched.Feed(ctx, jobs)
+
+	semaphore := make(chan struct{}, options.Workers)
+	resultsChan := make(chan indexedResult, options.Workers)
+	var wg sync.WaitGroup
+
+	go func() {
+		for job := range jobs {
+			// Занимаем место: блокируется, пока работают -workers горутин
+			semaphore <- struct{}{}
+			wg.Add(1)
+
+			go func(job poolJob) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+
+				sched.Start(ctx, job.url)
+				result := strategyWork(withHostLimits(ctx, sched.limits), p, job.url)
+				sched.Release(job.url)
+				resultsChan <- indexedResult{index: job.index, result: result}
+			}(job)
+		}
+
+		// Новых горутин больше не будет - можно ждать запущенные
+		wg.Wait()
+		close(resultsChan)
+	}()
+
+	collector := newResultCollector(len(urls), options.Unordered)
+	for item := range resultsChan {
+		collector.add(item)
+	}
+	for _, job := range sched.Remaining() {
+		collector.add(indexedResult{index: job.index, result: cancelledResult(job.url)})
+	}
+	p.Stop()
+
+	elapsed := time.Since(start)
+	logf("✅ Семафор: парсинг завершен за: %v\n\n", elapsed)
+	return collector.results(), elapsed
+}
+
+// Группа горутин с ограничением, как errgroup.Group из golang.org/x/sync
+// (проект обходится стандартной библиотекой): Go ждет свободного места,
+// первая ошибка отменяет контекст группы, Wait возвращает эту ошибку.
+type errGroup struct {
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+	limit  chan struct{}
+
+	once sync.Once
+	err  error
+}
+
+// Создаем группу с контекстом, который отменяется при первой ошибке
+func newErrGroup(ctx context.Context, limit int) (*errGroup, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &errGroup{cancel: cancel, limit: make(chan struct{}, limit)}, ctx
+}
+
+// Запускаем f в горутине, когда работает меньше limit горутин группы
+func (g *errGroup) Go(f func() error) {
+	g.limit <- struct{}{}
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+		defer func() { <-g.limit }()
+
+		if err := f(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+// Ждем все горутины группы и возвращаем первую ошибку
+func (g *errGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+	return g.err
+}
+
+// errgroup с ограничением: горутины запускает группа, результаты складываются
+// в общий сборщик под мьютексом (без канала результатов).
+// Ошибка страницы - это результат, а не ошибка группы: группу останавливает только отмена запуска.
+type errGroupStrategy struct{}
+
+func (errGroupStrategy) Name() string { return "errgroup" }
+func (errGroupStrategy) Description() string {
+	return "errgroup с лимитом -workers, результаты под мьютексом"
+}
+
+func (errGroupStrategy) Run(ctx context.Context, urls []string) ([]ParseResult, time.Duration) {
+	logf("👥 Запуск errgroup (лимит горутин: %d, на хост: %d)...\n", options.Workers, options.PerHost)
+	start := time.Now()
+	p := startProgress("errgroup", len(urls), len(urls))
+
+	group, groupCtx := newErrGroup(ctx, options.Workers)
+	sched := NewHostScheduler(urls, options.PerHost, hostDelay)
+	jobs := make(chan poolJob)
+	go sched.Feed(groupCtx, jobs)
+
+	var mutex sync.Mutex
+	collector := newResultCollector(len(urls), options.Unordered)
+
+	for job := range jobs {
+		job := job
+		group.Go(func() error {
+			sched.Start(groupCtx, job.url)
+			result := strategyWork(withHostLimits(groupCtx, sched.limits), p, job.url)
+			sched.Release(job.url)
+
+			mutex.Lock()
+			collector.add(indexedResult{index: job.index, result: result})
+			mutex.Unlock()
+
+			if result.Status == StatusCancelled {
+				return context.Cause(groupCtx)
+			}
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		logf("⏹️  errgroup остановлена: %v\n", err)
+	}
+
+	for _, job := range sched.Remaining() {
+		collector.add(indexedResult{index: job.index, result: cancelledResult(job.url)})
+	}
+	p.Stop()
+
+	elapsed := time.Since(start)
+	logf("✅ errgroup: парсинг завершен за: %v\n\n", elapsed)
+	return collector.results(), elapsed
+}
+
+// Конвейер из этапов fetch -> parse -> enrich -> sink (см. parsePipeline)
+type pipelineStrategy struct {
+	configs map[string]StageConfig
+}
+
+func (pipelineStrategy) Name() string { return "pipeline" }
+func (pipelineStrategy) Description() string {
+	return "конвейер fetch -> parse -> enrich -> sink"
+}
+
+func (s pipelineStrategy) Run(ctx context.Context, urls []string) ([]ParseResult, time.Duration) {
+	return parsePipeline(ctx, urls, s.configs)
+}
+
+// Функция для обработки одного URL в горутине стратегии: прогресс и строка о начале, как у пула
+func strategyWork(ctx context.Context, p *Progress, url string) ParseResult {
+	if !p.Active() {
+		logf("   Парсинг: %s (в горутине)\n", url)
+	}
+	p.Begin(url)
+	result := parseURL(ctx, url)
+	p.Finish(result)
+	return result
+}
+
+// Итоги одной стратегии для сравнительной таблицы
+type strategyRun struct {
+	strategy   Strategy
+	summary    RunSummary
+	ok         int
+	failed     int
+	skipped    int
+	goroutines int    // Наибольшее число горутин во время запуска
+	allocated  uint64 // Сколько памяти выделено за запуск
+}
+
+// Функция для запуска всех стратегий по очереди на одном списке URL.
+// Перед каждым запуском закрываются простаивающие соединения, чтобы следующая
+// стратегия не получила готовые соединения предыдущей. После отмены оставшиеся не запускаются.
+func compareStrategies(ctx context.Context, strategies []Strategy, urls []string) []strategyRun {
+	var runs []strategyRun
+	for _, strategy := range strategies {
+		if ctx.Err() != nil {
+			break
+		}
+		logf("▶️  Стратегия %s: %s\n", strategy.Name(), strategy.Description())
+		httpClient.CloseIdleConnections()
+
+		var before runtime.MemStats
+		runtime.ReadMemStats(&before)
+		stopSampler, peak := sampleGoroutines()
+
+		results, wall := strategy.Run(ctx, urls)
+
+		stopSampler()
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+
+		run := strategyRun{
+			strategy:   strategy,
+			summary:    summarize(results, wall),
+			goroutines: *peak,
+			allocated:  after.TotalAlloc - before.TotalAlloc,
+		}
+		for _, result := range results {
+			switch result.Status {
+			case StatusOK:
+				run.ok++
+			case StatusError:
+				run.failed++
+			default:
+				run.skipped++
+			}
+		}
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// Функция для замера наибольшего числа горутин: опрашивает runtime раз в миллисекунду.
+// Возвращает функцию остановки и указатель на результат (читать после остановки).
+func sampleGoroutines() (func(), *int) {
+	peak := runtime.NumGoroutine()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if n := runtime.NumGoroutine(); n > peak {
+					peak = n
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}, &peak
+}
+
+// Функция для вывода сравнительной таблицы стратегий.
+// Ускорение считается относительно первой стратегии (последовательной).
+func printStrategyTable(runs []strategyRun) {
+	fmt.Println("📈 Сравнение стратегий:")
+	fmt.Println(strings.Repeat("-", 80))
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "СТРАТЕГИЯ\tВРЕМЯ\tРАБОТА\tКОНКУРЕНТНОСТЬ\tУСКОРЕНИЕ\tOK/ОШИБКИ/ПРОПУЩЕНО\tГОРУТИН (ПИК)\tПАМЯТЬ")
+	for _, run := range runs {
+		speedup := "-"
+		if run.summary.Wall > 0 {
+			speedup = fmt.Sprintf("%.2fx", float64(runs[0].summary.Wall)/float64(run.summary.Wall))
+		}
+		fmt.Fprintf(table, "%s\t%v\t%v\t%.2f\t%s\t%d/%d/%d\t%d\t%s\n",
+			run.strategy.Name(),
+			run.summary.Wall.Round(time.Millisecond),
+			run.summary.Work.Round(time.Millisecond),
+			run.summary.Concurrency(),
+			speedup,
+			run.ok, run.failed, run.skipped,
+			run.goroutines,
+			formatBytes(int64(run.allocated)))
+	}
+	table.Flush()
+
+	fmt.Println()
+	fmt.Println("Горутины считаются по всей программе, включая тестовый сервер и HTTP соединения.")
+	fmt.Println()
+}